@@ -3,29 +3,68 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"regexp"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/auxence-m/cloudtail/stream"
+	"github.com/auxence-m/cloudtail/timeexpr"
 	"github.com/spf13/cobra"
 )
 
 type Options struct {
-	LogName      string
-	ResourceType string
-	Severity     string
-	Since        string
-	SinceTime    string
-	CustomFilter string
-	Follow       bool
-	Limit        int
-	Output       string
+	LogName         string
+	ResourceType    string
+	Severity        string
+	MinSeverity     string
+	MaxSeverity     string
+	Since           string
+	SinceTime       string
+	Until           string
+	UntilTime       string
+	Between         string
+	Tz              string
+	CustomFilter    string
+	Search          string
+	SearchIn        []string
+	Projects        []string
+	Organizations   []string
+	Folders         []string
+	BillingAccounts []string
+	Views           []string
+	ProjectPrefix   bool
+	Follow          bool
+	MaxReconnects   int
+	ReorderWindow   string
+	Checkpoint      string
+	Grep            string
+	GrepV           string
+	IgnoreCase      bool
+	After           int
+	Before          int
+	Context         int
+	NoReconnect     bool
+	Limit           int
+	Order           string
+	Output          string
+	Format          string
+	Wide            bool
+	Template        string
+	TemplateFile    string
+	Cluster         string
+	Namespace       string
+	Pod             string
+	Container       string
+	Location        string
+	PresetValues    map[string]string
+	Selector        string
 }
 
 // tailCmd represents the tail command
 var tailCmd = &cobra.Command{
-	Use:          "tail [projectID]",
-	Args:         cobra.ExactArgs(1),
+	Use:          "tail [projectID|resourceName...]",
+	Args:         cobra.ArbitraryArgs,
 	SilenceUsage: true,
 	Short:        "Display and stream Google Cloud Logging entries matching the specified filters",
 	Long:         `The tail command will fetch and list all Google Cloud Logging entries from the last 24 hours by default unless specified otherwise with the available flags`,
@@ -41,18 +80,65 @@ cloudtail tail projectID --resource-type=gce_instance --follow
 # Stream only ERROR severity logs
 cloudtail tail projectID --severity=ERROR --follow
 
-# Display the most recent 100 log entries
+# Stream WARNING logs and everything more severe
+cloudtail tail projectID --min-severity=WARN --follow
+
+# Display ERROR and CRITICAL logs, or a range of severities
+cloudtail tail projectID --severity=ERROR,CRITICAL
+cloudtail tail projectID --min-severity=NOTICE --max-severity=ERROR
+
+# Display the most recent 100 log entries, oldest first
 cloudtail tail projectID --limit=100
 
+# Display the most recent 100 log entries, newest first
+cloudtail tail projectID --limit=100 --order=desc
+
+# Display the last 20 log entries and continue streaming
+cloudtail tail projectID --limit=20 --follow
+
 # Display logs from the last 30 minutes
 cloudtail tail projectID --since=30m
 
 # Display logs from the last hour and continue streaming
 cloudtail tail projectID -since=1h --follow
 
+# Stream logs from several projects, each line prefixed with its project
+cloudtail tail proj-a proj-b proj-c --follow
+cloudtail tail --projects=proj-a,proj-b --severity=ERROR --since=1h
+
+# Display logs of an organization, a folder, or an aggregated log bucket view
+cloudtail tail --organization=123456789012 --since=1h
+cloudtail tail --folder=345678901234 --follow
+cloudtail tail --view=projects/sec-logs/locations/global/buckets/org-audit/views/_AllLogs --follow
+
+# Search logs for a text on the server, in all fields or in specific fields
+cloudtail tail projectID --since=7d --search="connection reset"
+cloudtail tail projectID --since=7d --search="connection reset" --search-in=textPayload,jsonPayload.message
+
+# Display the entries matching a regular expression, with 5 entries of the same pod around them
+cloudtail tail projectID --namespace=payments --since=1h --grep='timeout.*upstream' -C 5
+cloudtail tail projectID --follow --grep=error --grep-v='health ?check' --ignore-case
+
+# Ship logs to a file, resuming where the previous session stopped after a restart
+cloudtail tail projectID --follow --output=app.log --checkpoint=app.checkpoint
+
+# Stream logs from many pods in timestamp order
+cloudtail tail projectID --namespace=payments --follow --reorder-window=2s
+
+# Stream logs and give up after 5 consecutive failed reconnection attempts
+cloudtail tail projectID --follow --max-reconnects=5
+
 # Display logs newer than a specific point in time
 cloudtail tail projectID --since-time=2026-02-12T12:30:00Z
 
+# Display logs from the last 2 days, or since yesterday 14:00 in a given time zone
+cloudtail tail projectID --since=2d
+cloudtail tail projectID --since-time="yesterday 14:00" --tz=Europe/Paris
+
+# Display logs from a bounded time window, e.g. during an incident
+cloudtail tail projectID --between="yesterday 14:02,yesterday 14:20"
+cloudtail tail projectID --since=2h --until=1h
+
 # Filter logs by log name and resource type
 cloudtail tail projectID \
 	--log-name=projects/projectID/logs/cloudbuild \
@@ -86,6 +172,33 @@ cloudtail tail projectID \
 	--severity=ERROR \
 	--since=15m
 
+# Stream logs as newline-delimited JSON and pipe them into jq
+cloudtail tail projectID --follow --format=ndjson | jq '.jsonPayload'
+
+# Export the last hour of errors as CSV
+cloudtail tail projectID --severity=ERROR --since=1h --format=csv --output=errors.csv
+
+# Stream logs from the pods of a deployment, similar to kubectl logs
+cloudtail tail projectID --cluster=prod --namespace=payments --pod=api- --container=server --follow
+
+# Stream logs from a Cloud Run revision
+cloudtail tail projectID --run-service=api --revision=api-00042 --follow
+
+# Display logs from a Cloud Function (gen1 or gen2), an App Engine version or a Compute Engine instance
+cloudtail tail projectID --function=ingest --since=1h
+cloudtail tail projectID --gae-service=default --gae-version=v3
+cloudtail tail projectID --instance=web-1
+
+# Filter logs by entry or resource labels
+cloudtail tail projectID -l 'env=prod,tier!=cache,team in (payments,ledger)'
+
+# Show which pod, namespace and container each GKE entry comes from
+cloudtail tail projectID --resource-type=k8s_container --wide
+
+# Render each entry with a custom Go template
+cloudtail tail projectID \
+	--template='{{date "15:04:05" .Timestamp}} {{colorSeverity .Severity}} {{.Resource.Labels.pod_name}} {{trunc 120 .Message}}'
+
 # Retrieve recent logs using a fixed timestamp and save them
 cloudtail tail projectID \
 	--since-time=2026-01-13T12:30:00Z \
@@ -98,89 +211,379 @@ Notes:
   - --follow streams logs in real-time. Without --since or --since-time, 
     only new entries from the time of execution are shown.
   - --limit applies only to the initial historical fetch. Streaming ignores it.
+    The newest entries are shown in chronological order unless --order=desc is set.
+    With --follow, --limit shows the last entries before streaming new ones.
+  - With --follow, the stream is opened before historical logs are fetched, so
+    entries written in between are not lost and entries are never shown twice.
+  - With --follow, interrupted streams (unavailable service, exhausted quota or
+    a session ended by the server) are reopened with an exponential backoff and
+    resume after the last entry shown. Use --max-reconnects to give up after a
+    number of consecutive attempts, or --no-reconnect to stop at the first interruption.
+  - Logs of several projects or scopes are merged in timestamp order. With --follow,
+    a single stream covers all of them. Arguments accept project IDs and resource
+    names (organizations/ID, folders/ID, billingAccounts/ID or log bucket views).
+  - --filter is checked locally before any request. Syntax errors point at the
+    offending column and suggest fixes for common mistakes such as == or lowercase and.
+  - --search is evaluated by Cloud Logging with SEARCH() (a multi-word text is
+    searched as a phrase and cannot contain a backtick). With --search-in, entries
+    match when any of the --search-in fields contains the text (":" operator).
+    Prefer it to --grep for large historical queries.
+  - --grep and --grep-v match the message of entries after they are fetched.
+    -A, -B and -C show the entries around each match from the same resource
+    (e.g. the same pod), so entries of other workloads do not interleave.
+    Text output shows the resource labels and separates the groups with "--".
+    With --limit, matches are searched within the fetched entries.
+  - --checkpoint saves the timestamp and insertIds of the last entries printed.
+    When the file holds a position, the next session fetches the entries since
+    that position (ignoring --since, --since-time, --limit and --order) before
+    streaming, skips the entries already printed, and appends to --output.
+    An appended csv output keeps a single header row, and json cannot be appended
+    (use ndjson).
+  - --reorder-window holds streamed entries briefly and prints them sorted by timestamp.
+    Entries arriving after newer ones were printed are marked [late] (or "late": true).
+  - Live entries dropped by Cloud Logging (rate limits, or a stream not read fast enough)
+    are reported on stderr, or as {"event": "suppression", "reason", "suppressedCount"}
+    objects with --format=json|ndjson. Totals are reported when streaming stops.
+  - --since and --until accept d (days) and w (weeks) units. --since-time, --until-time
+    and --between accept RFC3339 timestamps, local times without an offset (interpreted
+    in --tz or the local time zone), today/yesterday with an optional clock time,
+    relative times such as -90m or "2h ago", and Unix epoch seconds or milliseconds.
+  - --until, --until-time and --between bound the end of the historical fetch
+    and cannot be combined with --follow. Without a start, the window starts 24 hours before its end.
+  - --severity matches exact levels. Use --min-severity and --max-severity for ranges.
+    Severities accept aliases (WARN, ERR, CRIT, FATAL, EMERG) and numeric levels (e.g. 400).
+  - --format=json|ndjson|logfmt|csv emit the full entry with a stable schema:
+    timestamp, severity, insertId, logName, resource {type, labels}, labels,
+    trace, spanId, sourceLocation {file, line, function}, httpRequest,
+    message (rendered from the payload) and textPayload, jsonPayload or protoPayload.
+    logfmt joins nested keys with dots (e.g. resource.labels.pod_name).
+    csv encodes labels, httpRequest and payload columns as JSON.
+  - --template and --template-file render each entry with a Go text/template.
+    Available helpers: colorSeverity, ago (relative time), date, trunc, pad,
+    padLeft, field (dotted path lookup, e.g. field "jsonPayload.user.id" .) and json.
 `,
 	RunE: tailRun,
 }
 
 func tailRun(cmd *cobra.Command, args []string) error {
-	if len(args) == 0 {
-		return fmt.Errorf("missing required argument: projectID")
-	}
-
 	flags := cmd.Flags()
 
 	options := Options{}
 
 	// Read flags
+	options.Projects, _ = flags.GetStringSlice("projects")
+	options.Organizations, _ = flags.GetStringSlice("organization")
+	options.Folders, _ = flags.GetStringSlice("folder")
+	options.BillingAccounts, _ = flags.GetStringSlice("billing-account")
+	options.Views, _ = flags.GetStringSlice("view")
+	options.ProjectPrefix, _ = flags.GetBool("project-prefix")
 	options.LogName, _ = flags.GetString("log-name")
 	options.ResourceType, _ = flags.GetString("resource-type")
 	options.Severity, _ = flags.GetString("severity")
+	options.MinSeverity, _ = flags.GetString("min-severity")
+	options.MaxSeverity, _ = flags.GetString("max-severity")
 	options.Since, _ = flags.GetString("since")
 	options.SinceTime, _ = flags.GetString("since-time")
+	options.Until, _ = flags.GetString("until")
+	options.UntilTime, _ = flags.GetString("until-time")
+	options.Between, _ = flags.GetString("between")
+	options.Tz, _ = flags.GetString("tz")
 	options.Follow, _ = flags.GetBool("follow")
+	options.MaxReconnects, _ = flags.GetInt("max-reconnects")
+	options.ReorderWindow, _ = flags.GetString("reorder-window")
+	options.Checkpoint, _ = flags.GetString("checkpoint")
+	options.Grep, _ = flags.GetString("grep")
+	options.GrepV, _ = flags.GetString("grep-v")
+	options.IgnoreCase, _ = flags.GetBool("ignore-case")
+	options.After, _ = flags.GetInt("after-context")
+	options.Before, _ = flags.GetInt("before-context")
+	options.Context, _ = flags.GetInt("context")
+
+	// --context sets the number of entries on both sides, unless --after-context or --before-context is set
+	if !flags.Changed("after-context") {
+		options.After = options.Context
+	}
+	if !flags.Changed("before-context") {
+		options.Before = options.Context
+	}
+	options.NoReconnect, _ = flags.GetBool("no-reconnect")
 	options.Limit, _ = flags.GetInt("limit")
+	options.Order, _ = flags.GetString("order")
 	options.Output, _ = flags.GetString("output")
+	options.Format, _ = flags.GetString("format")
+	options.Wide, _ = flags.GetBool("wide")
+	options.Template, _ = flags.GetString("template")
+	options.TemplateFile, _ = flags.GetString("template-file")
 	options.CustomFilter, _ = flags.GetString("filter")
+	options.Search, _ = flags.GetString("search")
+	options.SearchIn, _ = flags.GetStringSlice("search-in")
+	options.Cluster, _ = flags.GetString("cluster")
+	options.Namespace, _ = flags.GetString("namespace")
+	options.Pod, _ = flags.GetString("pod")
+	options.Container, _ = flags.GetString("container")
+	options.Location, _ = flags.GetString("location")
+	options.Selector, _ = flags.GetString("selector")
+
+	options.PresetValues = make(map[string]string)
+	for _, preset := range stream.Presets {
+		for _, presetFlag := range preset.Flags {
+			options.PresetValues[presetFlag.Name], _ = flags.GetString(presetFlag.Name)
+		}
+	}
 
-	projectID := args[0]
+	scopes, err := validateScopes(args, &options)
+	if err != nil {
+		return err
+	}
 
-	return fetchAndTailLogs(&options, projectID)
+	// Lines are prefixed with their project by default when several scopes are tailed
+	if !flags.Changed("project-prefix") {
+		options.ProjectPrefix = len(scopes) > 1
+	}
+
+	return fetchAndTailLogs(&options, scopes)
 }
 
-// validateSeverityFlag ensures the --severity flag has a valid value
-func validateSeverityFlag(severity string) (string, error) {
-	upper := strings.ToUpper(severity)
+// validateSeverityFlag ensures the --severity flag is a valid severity or a comma-separated list of severities.
+// Severities are case-insensitive and may be given as aliases (WARN, FATAL, etc.) or numeric levels (400, 500, etc.).
+func validateSeverityFlag(severity string) ([]string, error) {
+	var severities []string
+
+	for _, value := range strings.Split(severity, ",") {
+		parsed, err := stream.ParseSeverity(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for --severity flag: %q. (valid values: INFO, WARNING, ERROR, etc. or a comma-separated list such as ERROR,CRITICAL)", severity)
+		}
 
-	validSeverities := map[string]struct{}{
-		"DEFAULT":   {},
-		"DEBUG":     {},
-		"INFO":      {},
-		"NOTICE":    {},
-		"WARNING":   {},
-		"ERROR":     {},
-		"CRITICAL":  {},
-		"ALERT":     {},
-		"EMERGENCY": {},
+		if !slices.Contains(severities, parsed) {
+			severities = append(severities, parsed)
+		}
 	}
 
-	_, found := validSeverities[upper]
-	if !found {
-		return "", fmt.Errorf("invalid value for --severity flag: %q. (valid values: INFO, WARNING, ERROR, etc.)", severity)
+	return severities, nil
+}
+
+// validateSeverityBoundFlag ensures the --min-severity and --max-severity flags have a valid value
+func validateSeverityBoundFlag(flag string, severity string) (string, error) {
+	parsed, err := stream.ParseSeverity(severity)
+	if err != nil {
+		return "", fmt.Errorf("invalid value for --%s flag: %q. (valid values: INFO, WARNING, ERROR, etc.)", flag, severity)
+	}
+
+	return parsed, nil
+}
+
+// validateSelectorFlag validates that the --selector flag is a kubectl-style label selector (e.g. env=prod,tier!=cache)
+func validateSelectorFlag(selector string) ([]stream.LabelRequirement, error) {
+	requirements, err := stream.ParseSelector(selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value for --selector flag: %q (e.g. env=prod,tier!=cache,team in (payments,ledger)): \n%w", selector, err)
 	}
 
-	return upper, nil
+	return requirements, nil
 }
 
-// validateSinceFlag validates that the --since flag is a duartion (e.g. "1h", "30m", or "20s") and converts it into a time.Duration.
+// validateSinceFlag validates that the --since flag is a duration (e.g. "1h", "30m", "2d" or "1w") and converts it into a time.Duration.
 func validateSinceFlag(since string) (time.Duration, error) {
-	parseDuration, err := time.ParseDuration(since)
+	parseDuration, err := timeexpr.ParseDuration(since)
 	if err != nil {
-		return 0, fmt.Errorf("invalid value for --since flag: %q (valid values: 1h, 30m, 20s, 1h15m30s, etc.): \n%w", since, err)
+		return 0, fmt.Errorf("invalid value for --since flag: %q (valid values: 1h, 30m, 20s, 1h15m30s, 2d, 1w, etc.): \n%w", since, err)
+	}
+
+	return parseDuration, nil
+}
+
+// validateFilterFlag validates the syntax of the --filter flag with the logging query language parser.
+func validateFilterFlag(customFilter string) error {
+	if _, err := stream.ParseQuery(customFilter); err != nil {
+		return fmt.Errorf("invalid value for --filter flag: %w", err)
+	}
+
+	return nil
+}
+
+// searchFieldPattern matches the field paths accepted by --search-in, e.g. textPayload or jsonPayload.message
+var searchFieldPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)*$`)
+
+// validateSearchInFlag validates that the --search-in flag lists field paths (e.g. textPayload,jsonPayload.message).
+func validateSearchInFlag(fields []string) ([]string, error) {
+	var searchFields []string
+	for _, field := range fields {
+		field = strings.TrimSpace(field)
+		if !searchFieldPattern.MatchString(field) {
+			return nil, fmt.Errorf("invalid value for --search-in flag: %q (must be a field path such as textPayload or jsonPayload.message)", field)
+		}
+		searchFields = append(searchFields, field)
 	}
 
-	if parseDuration < 0 {
-		return 0, fmt.Errorf("the --since flag duration must be positive (got %q)", since)
+	return searchFields, nil
+}
+
+// validateReorderWindowFlag validates that the --reorder-window flag is a duration (e.g. "2s" or "500ms") and converts it into a time.Duration.
+func validateReorderWindowFlag(reorderWindow string) (time.Duration, error) {
+	parseDuration, err := timeexpr.ParseDuration(reorderWindow)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value for --reorder-window flag: %q (valid values: 2s, 500ms, 1m, etc.): \n%w", reorderWindow, err)
 	}
 
 	return parseDuration, nil
 }
 
-// validateSinceTimeFlag validates that the --since-time flag is a valid RFC3339 timestamp (e.g. 2024-01-09T10:30:00Z).
-func validateSinceTimeFlag(sinceTime string) (time.Time, error) {
-	parsedTime, err := time.Parse(time.RFC3339, sinceTime)
+// validateSinceTimeFlag validates that the --since-time flag is a valid time expression
+// (e.g. 2024-01-09T10:30:00Z, "2024-01-09 10:30", "yesterday 14:00", -90m or 1704796200) interpreted in loc.
+func validateSinceTimeFlag(sinceTime string, loc *time.Location) (time.Time, error) {
+	parsedTime, err := timeexpr.Parse(sinceTime, time.Now(), loc)
 	if err != nil {
-		return time.Time{}, fmt.Errorf("invalid value for --sinceTime flag: %q (must be RFC3339 format): \n%w", sinceTime, err)
+		return time.Time{}, fmt.Errorf("invalid value for --since-time flag: %q (e.g. 2024-01-09T10:30:00Z, \"2024-01-09 10:30\", \"yesterday 14:00\", today, -90m): \n%w", sinceTime, err)
 	}
 
 	return parsedTime, nil
 }
 
-func fetchAndTailLogs(options *Options, projectID string) error {
+// validateUntilFlag validates that the --until flag is a duration (e.g. "1h", "30m", "2d" or "1w") and converts it into a time.Duration.
+func validateUntilFlag(until string) (time.Duration, error) {
+	parseDuration, err := timeexpr.ParseDuration(until)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value for --until flag: %q (valid values: 1h, 30m, 20s, 1h15m30s, 2d, 1w, etc.): \n%w", until, err)
+	}
+
+	return parseDuration, nil
+}
+
+// validateUntilTimeFlag validates that the --until-time flag is a valid time expression
+// (e.g. 2024-01-09T10:30:00Z, "2024-01-09 10:30", "yesterday 14:00", -90m or 1704796200) interpreted in loc.
+func validateUntilTimeFlag(untilTime string, loc *time.Location) (time.Time, error) {
+	parsedTime, err := timeexpr.Parse(untilTime, time.Now(), loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid value for --until-time flag: %q (e.g. 2024-01-09T10:30:00Z, \"2024-01-09 10:30\", \"yesterday 14:20\", -30m): \n%w", untilTime, err)
+	}
+
+	return parsedTime, nil
+}
+
+// validateBetweenFlag validates that the --between flag is a pair of time expressions separated by a comma
+// (e.g. "yesterday 14:02,yesterday 14:20") and returns the start and end of the window.
+func validateBetweenFlag(between string, loc *time.Location) (time.Time, time.Time, error) {
+	startStr, endStr, found := strings.Cut(between, ",")
+	if !found {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid value for --between flag: %q (must be START,END)", between)
+	}
+
+	now := time.Now()
+
+	start, err := timeexpr.Parse(startStr, now, loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid start for --between flag: %q: \n%w", startStr, err)
+	}
+
+	end, err := timeexpr.Parse(endStr, now, loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid end for --between flag: %q: \n%w", endStr, err)
+	}
+
+	return start, end, nil
+}
+
+// validateTzFlag validates that the --tz flag is an IANA time zone name (e.g. Europe/Paris) or "Local"
+func validateTzFlag(tz string) (*time.Location, error) {
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value for --tz flag: %q (must be an IANA time zone such as UTC or Europe/Paris): \n%w", tz, err)
+	}
+
+	return loc, nil
+}
+
+// validateFormatFlag ensures the --format flag is one of the supported output formats
+func validateFormatFlag(format string) (string, error) {
+	lower := strings.ToLower(format)
+
+	if !slices.Contains(stream.Formats, lower) {
+		return "", fmt.Errorf("invalid value for --format flag: %q. (valid values: %s)", format, strings.Join(stream.Formats, ", "))
+	}
+
+	return lower, nil
+}
+
+// validateOutputFlag rejects an --output that names an output format, e.g. "-o wide" as in kubectl,
+// which would otherwise silently write text output to a file named "wide"
+func validateOutputFlag(output string) error {
+	lower := strings.ToLower(output)
+
+	if lower == stream.FormatWide {
+		return fmt.Errorf("invalid value for --output flag: %q. (-o/--output is the output file, use --wide or --format=wide, or --output=./%s to write to a file with that name)", output, output)
+	}
+
+	if slices.Contains(stream.Formats, lower) {
+		return fmt.Errorf("invalid value for --output flag: %q. (-o/--output is the output file, use --format=%s, or --output=./%s to write to a file with that name)", output, lower, output)
+	}
+
+	return nil
+}
+
+// validateScopes merges the scopes given as arguments (project IDs or resource names) and with the
+// --projects, --organization, --folder, --billing-account and --view flags, ignoring duplicates.
+func validateScopes(args []string, options *Options) ([]stream.Scope, error) {
+	var scopes []stream.Scope
+
+	add := func(scope stream.Scope) {
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	for _, name := range append(args, options.Projects...) {
+		scope, err := stream.ParseScope(strings.TrimSpace(name))
+		if err != nil {
+			return nil, err
+		}
+		add(scope)
+	}
+
+	for _, view := range options.Views {
+		scope, err := stream.ParseScope(strings.TrimSpace(view))
+		if err != nil || scope.Kind != stream.ScopeView {
+			return nil, fmt.Errorf("invalid value for --view flag: %q (expected projects/p/locations/l/buckets/b/views/v)", view)
+		}
+		add(scope)
+	}
+
+	kinds := []struct {
+		flag string
+		kind string
+		ids  []string
+	}{
+		{flag: "organization", kind: stream.ScopeOrganization, ids: options.Organizations},
+		{flag: "folder", kind: stream.ScopeFolder, ids: options.Folders},
+		{flag: "billing-account", kind: stream.ScopeBillingAccount, ids: options.BillingAccounts},
+	}
+
+	for _, k := range kinds {
+		for _, id := range k.ids {
+			scope, err := stream.NewScope(k.kind, strings.TrimSpace(id))
+			if err != nil {
+				return nil, fmt.Errorf("invalid value for --%s flag: \n%w", k.flag, err)
+			}
+			add(scope)
+		}
+	}
+
+	if len(scopes) == 0 {
+		return nil, fmt.Errorf("missing required argument: projectID (or --organization, --folder, --billing-account or --view)")
+	}
+
+	return scopes, nil
+}
+
+func fetchAndTailLogs(options *Options, scopes []stream.Scope) (err error) {
 	var (
 		parseDuration time.Duration
 		parseTime     time.Time
-		parseSeverity string
-		err           error
+		untilDuration time.Duration
+		untilTime     time.Time
+		parseSeverity []string
+		minSeverity   string
+		maxSeverity   string
 	)
 
 	if options == nil {
@@ -194,9 +597,13 @@ func fetchAndTailLogs(options *Options, projectID string) error {
 	since := strings.TrimSpace(options.Since)
 	sinceTime := strings.TrimSpace(options.SinceTime)
 	output := strings.TrimSpace(options.Output)
+	format := strings.TrimSpace(options.Format)
+	tmpl := options.Template
+	templateFile := strings.TrimSpace(options.TemplateFile)
 	customFilter := strings.TrimSpace(options.CustomFilter)
+	selector := strings.TrimSpace(options.Selector)
 
-	// Validate severity flag
+	// Validate severity flags
 	if severity != "" {
 		parseSeverity, err = validateSeverityFlag(severity)
 		if err != nil {
@@ -204,6 +611,73 @@ func fetchAndTailLogs(options *Options, projectID string) error {
 		}
 	}
 
+	if value := strings.TrimSpace(options.MinSeverity); value != "" {
+		minSeverity, err = validateSeverityBoundFlag("min-severity", value)
+		if err != nil {
+			return err
+		}
+	}
+
+	if value := strings.TrimSpace(options.MaxSeverity); value != "" {
+		maxSeverity, err = validateSeverityBoundFlag("max-severity", value)
+		if err != nil {
+			return err
+		}
+	}
+
+	if minSeverity != "" && maxSeverity != "" && stream.SeverityLevel(minSeverity) > stream.SeverityLevel(maxSeverity) {
+		return fmt.Errorf("the --min-severity flag (%s) must not be higher than the --max-severity flag (%s)", minSeverity, maxSeverity)
+	}
+
+	// Validate tz flag, used to interpret times without an offset
+	loc := time.Local
+	if tz := strings.TrimSpace(options.Tz); tz != "" {
+		loc, err = validateTzFlag(tz)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Validate until flag
+	if until := strings.TrimSpace(options.Until); until != "" {
+		untilDuration, err = validateUntilFlag(until)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Validate untilTime flag
+	if value := strings.TrimSpace(options.UntilTime); value != "" {
+		untilTime, err = validateUntilTimeFlag(value, loc)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Validate between flag, a shorthand for --since-time and --until-time
+	if between := strings.TrimSpace(options.Between); between != "" {
+		parseTime, untilTime, err = validateBetweenFlag(between, loc)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Validate selector flag
+	var requirements []stream.LabelRequirement
+	if selector != "" {
+		requirements, err = validateSelectorFlag(selector)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Validate filter flag before any request, the API only reports an opaque InvalidArgument error
+	if customFilter != "" {
+		if err := validateFilterFlag(customFilter); err != nil {
+			return err
+		}
+	}
+
 	// Validate since flag
 	if since != "" {
 		parseDuration, err = validateSinceFlag(since)
@@ -214,8 +688,44 @@ func fetchAndTailLogs(options *Options, projectID string) error {
 
 	// Validate sinceTime flag
 	if sinceTime != "" {
-		parseTime, err = validateSinceTimeFlag(sinceTime)
+		parseTime, err = validateSinceTimeFlag(sinceTime, loc)
+		if err != nil {
+			return err
+		}
+	}
+
+	// --wide is a shorthand for --format=wide
+	if options.Wide {
+		format = stream.FormatWide
+	}
+
+	// Validate format flag
+	if format != "" {
+		format, err = validateFormatFlag(format)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Validate output flag
+	if output != "" {
+		if err := validateOutputFlag(output); err != nil {
+			return err
+		}
+	}
+
+	// Read template file
+	if templateFile != "" {
+		content, err := os.ReadFile(templateFile)
 		if err != nil {
+			return fmt.Errorf("could not read template file: \n%w", err)
+		}
+		tmpl = string(content)
+	}
+
+	// Validate template before the output file is opened (and truncated)
+	if tmpl != "" {
+		if err := stream.ValidateTemplate(tmpl); err != nil {
 			return err
 		}
 	}
@@ -226,22 +736,175 @@ func fetchAndTailLogs(options *Options, projectID string) error {
 
 	}
 
+	// Validate max-reconnects flag, make sure default value (-1) is ignored
+	if options.MaxReconnects != -1 && options.MaxReconnects < 0 {
+		return fmt.Errorf("invalid value for --max-reconnects flag: %d. (must be positive)", options.MaxReconnects)
+	}
+
+	maxReconnects := options.MaxReconnects
+	if options.NoReconnect {
+		maxReconnects = 0
+	}
+
+	// Validate reorder-window flag, entries are only reordered while streaming
+	var reorderWindow time.Duration
+	if options.ReorderWindow != "" {
+		if !options.Follow {
+			return fmt.Errorf("--reorder-window requires --follow, historical logs are already in order")
+		}
+
+		reorderWindow, err = validateReorderWindowFlag(options.ReorderWindow)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Validate order flag
+	order := strings.ToLower(strings.TrimSpace(options.Order))
+	if order != stream.OrderAsc && order != stream.OrderDesc {
+		return fmt.Errorf("invalid value for --order flag: %q. (valid values: asc, desc)", options.Order)
+	}
+
+	// Validate search flags
+	searchFields, err := validateSearchInFlag(options.SearchIn)
+	if err != nil {
+		return err
+	}
+
+	if len(searchFields) > 0 && options.Search == "" {
+		return fmt.Errorf("--search-in requires --search")
+	}
+
+	// A multi-word text is searched as a phrase quoted with backticks, which cannot contain one
+	if len(searchFields) == 0 && strings.ContainsAny(options.Search, " \t") && strings.Contains(options.Search, "`") {
+		return fmt.Errorf("invalid value for --search flag: %q (a text with several words is searched as a phrase and cannot contain a backtick)", options.Search)
+	}
+
+	// Validate grep flags
+	var grep *stream.GrepOptions
+	if options.After < 0 || options.Before < 0 {
+		return fmt.Errorf("invalid value for --after-context, --before-context or --context flag (must be positive)")
+	}
+
+	if options.Grep != "" || options.GrepV != "" {
+		grep = &stream.GrepOptions{
+			Pattern:       options.Grep,
+			InvertPattern: options.GrepV,
+			IgnoreCase:    options.IgnoreCase,
+			Before:        options.Before,
+			After:         options.After,
+		}
+
+		// Invalid patterns are reported before the output file is opened (and truncated)
+		if err := grep.Validate(); err != nil {
+			return err
+		}
+	}
+
+	// Load checkpoint, a saved position replaces the start of the historical fetch
+	var checkpoint *stream.Checkpoint
+	resuming := false
+	if options.Checkpoint != "" {
+		checkpoint, err = stream.LoadCheckpoint(options.Checkpoint)
+		if err != nil {
+			return err
+		}
+
+		if resume, ok := checkpoint.Resume(); ok {
+			resuming = true
+			fmt.Fprintln(os.Stderr, "Resuming from checkpoint:", resume.Format(time.RFC3339Nano))
+			parseDuration, parseTime = 0, resume
+			options.Limit, order = -1, stream.OrderAsc
+		}
+	}
+
 	// Build filter object
 	filter := stream.Filter{
 		LogName:      logName,
 		ResourceType: resourceType,
-		Severity:     parseSeverity,
+		Severities:   parseSeverity,
+		MinSeverity:  minSeverity,
+		MaxSeverity:  maxSeverity,
 		Since:        parseDuration,
 		SinceTime:    parseTime,
+		Until:        untilDuration,
+		UntilTime:    untilTime,
 		CustomFilter: customFilter,
+		Search:       options.Search,
+		SearchFields: searchFields,
+		Cluster:      strings.TrimSpace(options.Cluster),
+		Namespace:    strings.TrimSpace(options.Namespace),
+		Pod:          strings.TrimSpace(options.Pod),
+		Container:    strings.TrimSpace(options.Container),
+		Location:     strings.TrimSpace(options.Location),
+		PresetValues: make(map[string]string),
+		Selector:     requirements,
+	}
+
+	for name, value := range options.PresetValues {
+		filter.PresetValues[name] = strings.TrimSpace(value)
+	}
+
+	// Validate resource preset flags
+	preset, err := stream.ActivePreset(filter.PresetValues)
+	if err != nil {
+		return fmt.Errorf("invalid resource flags: %w", err)
 	}
+
+	if preset != nil && filter.HasKubernetes() {
+		return fmt.Errorf("%s flags cannot be combined with --cluster, --namespace, --pod, --container or --location", preset.Name)
+	}
+
+	if preset != nil && resourceType != "" && !preset.HasResourceType(resourceType) {
+		return fmt.Errorf("%s flags cannot be combined with --resource-type=%s", preset.Name, resourceType)
+	}
+
+	// Validate time window
+	if filter.Until != 0 || !filter.UntilTime.IsZero() {
+		if options.Follow {
+			return fmt.Errorf("--until, --until-time and --between cannot be used with --follow, streaming has no end time")
+		}
+
+		start, end := filter.TimeWindow(time.Now())
+		if !start.IsZero() && end.Before(start) {
+			return fmt.Errorf("the end of the time window (%s) is before its start (%s)", end.Format(time.RFC3339), start.Format(time.RFC3339))
+		}
+	}
+
+	// Kubernetes shortcuts only apply to k8s_container entries
+	if filter.HasKubernetes() && resourceType != "" && resourceType != "k8s_container" {
+		return fmt.Errorf("--cluster, --namespace, --pod, --container and --location only apply to --resource-type=k8s_container (got %q)", resourceType)
+	}
+
+	// Validate pod flag when used as a regular expression
+	if filter.Pod != "" {
+		if _, err := regexp.Compile(filter.Pod); err != nil {
+			return fmt.Errorf("invalid value for --pod flag: %q (must be a pod name prefix or a regular expression): \n%w", filter.Pod, err)
+		}
+	}
+
 	filterStr := stream.BuildFilterString(&filter)
 	//fmt.Println(filterStr)
 
 	// Set proper output
 	terminalOut := os.Stdout
+	appending := false
 	if output != "" {
-		file, err := os.Create(output)
+		// A resumed session appends to the output of the previous sessions
+		flag := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+		if resuming {
+			flag = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+
+			if info, err := os.Stat(output); err == nil && info.Size() > 0 {
+				// A second JSON array would make the file invalid, csv continues without a second header
+				if format == stream.FormatJSON && tmpl == "" {
+					return fmt.Errorf("--format=json cannot append to the existing --output file %q when resuming from --checkpoint, use --format=ndjson instead", output)
+				}
+				appending = true
+			}
+		}
+
+		file, err := os.OpenFile(output, flag, 0666)
 		if err != nil {
 			return fmt.Errorf("could not open output file: \n%w", err)
 		}
@@ -255,21 +918,41 @@ func fetchAndTailLogs(options *Options, projectID string) error {
 		os.Stdout = file
 	}
 
-	// disable limit when streaming logs
-	if options.Follow {
-		options.Limit = -1
+	// Context entries come from the same resource as their match, its labels tell the groups of several resources apart
+	printer, err := stream.NewPrinter(os.Stdout, stream.PrintOptions{
+		Format:         format,
+		ResourceLabels: preset != nil || filter.HasKubernetes() || (grep != nil && (grep.Before > 0 || grep.After > 0)),
+		ProjectPrefix:  options.ProjectPrefix,
+		Template:       tmpl,
+		Grep:           grep,
+		Append:         appending,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Close the printer on every path to terminate formats such as json arrays, even when fetching fails
+	defer func() {
+		if closeErr := printer.Close(); closeErr != nil && err == nil {
+			err = fmt.Errorf("error writing logs: \n%w", closeErr)
+		}
+	}()
+
+	// Fetch historical logs if requested, --limit with --follow shows the last entries before streaming (like tail -n -f)
+	var history *stream.History
+	if filter.Since != 0 || !filter.SinceTime.IsZero() || options.Limit > 0 || !options.Follow {
+		history = &stream.History{Filter: filterStr, Limit: options.Limit, Order: order}
 	}
 
-	// Fetch historical logs if requested
-	if filter.Since != 0 || !filter.SinceTime.IsZero() || !options.Follow {
-		if err := stream.GetEntries(os.Stdout, projectID, filterStr, options.Limit); err != nil {
+	if !options.Follow {
+		if err := stream.GetEntries(printer, scopes, history.Filter, history.Limit, history.Order, checkpoint); err != nil {
 			return fmt.Errorf("error fetching logs: \n%w", err)
 		}
 	}
 
-	// Stream logs if --follow is set
+	// Stream logs if --follow is set, the history is fetched once the stream is open so no entry is missed in between
 	if options.Follow {
-		if err := stream.TailLogs(os.Stdout, projectID, filterStr, options.Limit); err != nil {
+		if err := stream.TailLogs(printer, scopes, filterStr, stream.TailOptions{Limit: -1, History: history, ReorderWindow: reorderWindow, Checkpoint: checkpoint, MaxReconnects: maxReconnects}); err != nil {
 			return fmt.Errorf("error tailing logs: \n%w", err)
 		}
 	}
@@ -280,16 +963,69 @@ func fetchAndTailLogs(options *Options, projectID string) error {
 func init() {
 	rootCmd.AddCommand(tailCmd)
 
+	tailCmd.Flags().StringSlice("projects", nil, "Comma-separated list of projects to display logs from, in addition to the projectID arguments")
+	tailCmd.Flags().StringSlice("organization", nil, "Display logs of an organization (numeric ID), can be repeated")
+	tailCmd.Flags().StringSlice("folder", nil, "Display logs of a folder (numeric ID), can be repeated")
+	tailCmd.Flags().StringSlice("billing-account", nil, "Display logs of a billing account (e.g. 0123AB-4567CD-89EF01), can be repeated")
+	tailCmd.Flags().StringSlice("view", nil, "Display logs of a log bucket view (projects/p/locations/l/buckets/b/views/v), can be repeated")
+	tailCmd.Flags().Bool("project-prefix", false, "Prefix each line with its project ID (defaults to true when displaying logs from several projects)")
 	tailCmd.Flags().String("log-name", "", "Filter logs by log name")
 	tailCmd.Flags().String("resource-type", "", "Filter logs by resource type")
-	tailCmd.Flags().String("severity", "", "Filter logs by severity level (DEFAULT, DEBUG, INFO, NOTICE, WARNING, ERROR, CRITICAL, ALERT, EMERGENCY)")
-	tailCmd.Flags().String("since", "", "Show logs newer than a relative duration (e.g. 1h, 30m, 20s, 1h15m30s). Only one of since-time / since may be used")
-	tailCmd.Flags().String("since-time", "", "Show logs newer than an RFC3339 timestamp (e.g. 2026-01-13T12:30:00Z). Only one of since-time / since may be used")
+	tailCmd.Flags().String("severity", "", "Filter logs by exact severity level or comma-separated list of levels (DEFAULT, DEBUG, INFO, NOTICE, WARNING, ERROR, CRITICAL, ALERT, EMERGENCY)")
+	tailCmd.Flags().String("min-severity", "", "Show logs at or above a severity level (e.g. WARNING shows WARNING, ERROR, CRITICAL, etc.)")
+	tailCmd.Flags().String("max-severity", "", "Show logs at or below a severity level (e.g. INFO shows DEFAULT, DEBUG and INFO)")
+	tailCmd.Flags().String("since", "", "Show logs newer than a relative duration (e.g. 1h, 30m, 20s, 1h15m30s, 2d, 1w). Only one of since-time / since may be used")
+	tailCmd.Flags().String("since-time", "", `Show logs newer than a point in time (e.g. 2026-01-13T12:30:00Z, "2026-01-13 12:30", "yesterday 14:00", today, -90m, 1768307400). Only one of since-time / since may be used`)
 	tailCmd.Flags().String("filter", "", `Apply a raw filter expression for advanced queries (e.g. severity>="WARNING" AND severity<="ERROR" AND timestamp>="2026-05-18T12:00:00Z")`)
 
+	tailCmd.Flags().String("until", "", "Show logs older than a relative duration (e.g. 1h, 30m, 20s, 1h15m30s, 2d, 1w). Only one of until-time / until may be used")
+	tailCmd.Flags().String("until-time", "", `Show logs older than a point in time (e.g. 2026-01-13T14:20:00Z, "2026-01-13 14:20", "yesterday 14:20", -30m). Only one of until-time / until may be used`)
+	tailCmd.Flags().String("between", "", `Show logs between two points in time separated by a comma (e.g. "yesterday 14:02,yesterday 14:20")`)
+	tailCmd.Flags().String("tz", "", "Time zone used to interpret times without an offset (e.g. UTC, Europe/Paris). Defaults to the local time zone")
+
 	tailCmd.MarkFlagsMutuallyExclusive("since", "since-time")
+	tailCmd.MarkFlagsMutuallyExclusive("until", "until-time")
+	tailCmd.MarkFlagsMutuallyExclusive("between", "since")
+	tailCmd.MarkFlagsMutuallyExclusive("between", "since-time")
+	tailCmd.MarkFlagsMutuallyExclusive("between", "until")
+	tailCmd.MarkFlagsMutuallyExclusive("between", "until-time")
+
+	tailCmd.Flags().String("cluster", "", "Filter Kubernetes container logs by cluster name")
+	tailCmd.Flags().String("namespace", "", "Filter Kubernetes container logs by namespace")
+	tailCmd.Flags().String("pod", "", "Filter Kubernetes container logs by pod name prefix or regular expression (e.g. api- or '^api-[a-z0-9]+$')")
+	tailCmd.Flags().String("container", "", "Filter Kubernetes container logs by container name")
+	tailCmd.Flags().String("location", "", "Filter Kubernetes container logs by cluster location (e.g. us-central1 or us-central1-a)")
+
+	tailCmd.Flags().StringP("selector", "l", "", "Filter logs by entry or resource labels with a label selector (e.g. env=prod,tier!=cache,team in (payments,ledger))")
+
+	for _, preset := range stream.Presets {
+		for _, presetFlag := range preset.Flags {
+			tailCmd.Flags().String(presetFlag.Name, "", presetFlag.Usage)
+		}
+	}
+
+	tailCmd.Flags().String("search", "", "Filter logs containing a text, searched by Cloud Logging in all fields (e.g. \"connection reset\")")
+	tailCmd.Flags().StringSlice("search-in", nil, "Match --search in any of a comma-separated list of fields instead of all fields (e.g. textPayload,jsonPayload.message)")
+	tailCmd.Flags().String("grep", "", "Only display entries whose message matches a regular expression, matches are highlighted")
+	tailCmd.Flags().String("grep-v", "", "Do not display entries whose message matches a regular expression")
+	tailCmd.Flags().Bool("ignore-case", false, "Match --grep and --grep-v regular expressions case-insensitively")
+	tailCmd.Flags().IntP("after-context", "A", 0, "Display a number of entries of the same resource after each entry matching --grep")
+	tailCmd.Flags().IntP("before-context", "B", 0, "Display a number of entries of the same resource before each entry matching --grep")
+	tailCmd.Flags().IntP("context", "C", 0, "Display a number of entries of the same resource around each entry matching --grep")
 
 	tailCmd.Flags().BoolP("follow", "f", false, "Stream new log entries as they are generated")
+	tailCmd.Flags().Int("max-reconnects", -1, "Maximum number of consecutive reconnection attempts when the stream is interrupted (defaults to -1, no maximum)")
+	tailCmd.Flags().Bool("no-reconnect", false, "Stop streaming instead of reconnecting when the stream is interrupted")
+	tailCmd.Flags().String("reorder-window", "", "Hold streamed entries for a duration (e.g. 2s) to print them in timestamp order")
+	tailCmd.Flags().String("checkpoint", "", "Save the position of the session to the specified file and resume from it on the next start")
 	tailCmd.Flags().IntP("limit", "n", -1, "Maximum number of logs to display (defaults to -1, showing all logs).")
-	tailCmd.Flags().StringP("output", "o", "", "Write logs to the specified file (defaults to stdout).")
+	tailCmd.Flags().String("order", stream.OrderAsc, "Order of the historical logs, asc (oldest first) or desc (newest first)")
+	tailCmd.Flags().StringP("output", "o", "", "Write logs to the specified file (defaults to stdout). Unlike kubectl, -o is not the output format: use --wide or --format=wide for wide output")
+	tailCmd.Flags().String("format", stream.FormatText, "Output format (text, wide, json, ndjson, logfmt, csv)")
+	tailCmd.Flags().Bool("wide", false, "Show resource labels, log name, trace/span IDs and source location on each line (same as --format=wide)")
+	tailCmd.Flags().String("template", "", `Render each entry with a Go template (e.g. '{{.Timestamp}} {{.Resource.Labels.pod_name}} {{.Message}}')`)
+	tailCmd.Flags().String("template-file", "", "Render each entry with a Go template read from the specified file")
+
+	tailCmd.MarkFlagsMutuallyExclusive("format", "wide", "template", "template-file")
+	tailCmd.MarkFlagsMutuallyExclusive("max-reconnects", "no-reconnect")
 }
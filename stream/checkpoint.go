@@ -0,0 +1,169 @@
+package stream
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// checkpointSize is the number of recently printed entries remembered by a checkpoint
+	checkpointSize = 1000
+	// checkpointInterval is the minimum time between two saves of a checkpoint while entries are printed
+	checkpointInterval = time.Second
+)
+
+// Checkpoint persists the position of a session in a file, so that the next session resumes where it stopped
+type Checkpoint struct {
+	path string
+	// Timestamp is the timestamp of the newest entry printed
+	Timestamp time.Time `json:"timestamp"`
+	// Recent lists the entries printed last, oldest first, to skip them when resuming
+	Recent []checkpointEntry `json:"recent"`
+
+	loaded  map[string]bool
+	savedAt time.Time
+	dirty   bool
+}
+
+type checkpointEntry struct {
+	InsertID  string    `json:"insertId"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// LoadCheckpoint reads the checkpoint stored at path, a missing file gives an empty checkpoint
+func LoadCheckpoint(path string) (*Checkpoint, error) {
+	c := &Checkpoint{path: path}
+
+	content, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("could not read checkpoint: \n%w", err)
+	}
+
+	if len(content) > 0 {
+		if err := json.Unmarshal(content, c); err != nil {
+			return nil, fmt.Errorf("invalid checkpoint %s: \n%w", path, err)
+		}
+	}
+
+	c.loaded = make(map[string]bool, len(c.Recent))
+	for _, recent := range c.Recent {
+		c.loaded[entryKey(&Entry{InsertID: recent.InsertID, Timestamp: recent.Timestamp})] = true
+	}
+
+	return c, nil
+}
+
+// Resume returns the time to resume from, the timestamp of the oldest recent entry,
+// or false when the checkpoint holds no position yet
+func (c *Checkpoint) Resume() (time.Time, bool) {
+	if c.Timestamp.IsZero() {
+		return time.Time{}, false
+	}
+
+	resume := c.Timestamp
+	for _, recent := range c.Recent {
+		if recent.Timestamp.Before(resume) {
+			resume = recent.Timestamp
+		}
+	}
+
+	return resume, true
+}
+
+// Save writes the checkpoint atomically, through a temporary file renamed over the previous checkpoint
+func (c *Checkpoint) Save() error {
+	if !c.dirty {
+		return nil
+	}
+
+	content, err := json.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("failed to encode checkpoint: \n%w", err)
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("could not write checkpoint: \n%w", err)
+	}
+	defer os.Remove(file.Name()) // No-op once renamed
+
+	if _, err := file.Write(content); err != nil {
+		file.Close()
+		return fmt.Errorf("could not write checkpoint: \n%w", err)
+	}
+
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return fmt.Errorf("could not write checkpoint: \n%w", err)
+	}
+
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("could not write checkpoint: \n%w", err)
+	}
+
+	if err := os.Rename(file.Name(), c.path); err != nil {
+		return fmt.Errorf("could not write checkpoint: \n%w", err)
+	}
+
+	c.savedAt = time.Now()
+	c.dirty = false
+
+	return nil
+}
+
+// save saves a checkpoint that may be nil
+func (c *Checkpoint) save() error {
+	if c == nil {
+		return nil
+	}
+
+	return c.Save()
+}
+
+// checkpointPrinter skips the entries held by the checkpoint and records the entries printed
+func checkpointPrinter(printer Printer, checkpoint *Checkpoint) Printer {
+	return printerFunc(func(entry *Entry) error {
+		if checkpoint.contains(entry) {
+			return nil
+		}
+
+		if err := printer.Print(entry); err != nil {
+			return err
+		}
+
+		return checkpoint.record(entry)
+	})
+}
+
+// contains reports whether the entry was printed by the session that saved the checkpoint
+func (c *Checkpoint) contains(entry *Entry) bool {
+	return c != nil && entry.InsertID != "" && c.loaded[entryKey(entry)]
+}
+
+// record remembers a printed entry, and saves the checkpoint when it was not saved for checkpointInterval
+func (c *Checkpoint) record(entry *Entry) error {
+	if c == nil {
+		return nil
+	}
+
+	c.Recent = append(c.Recent, checkpointEntry{InsertID: entry.InsertID, Timestamp: entry.Timestamp})
+	if len(c.Recent) > checkpointSize {
+		c.Recent = c.Recent[len(c.Recent)-checkpointSize:]
+	}
+
+	if entry.Timestamp.After(c.Timestamp) {
+		c.Timestamp = entry.Timestamp
+	}
+	c.dirty = true
+
+	if time.Since(c.savedAt) < checkpointInterval {
+		return nil
+	}
+
+	return c.Save()
+}
@@ -0,0 +1,166 @@
+package stream
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadCheckpoint(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		name    string
+		exists  bool
+		content string
+		wantErr bool
+	}{
+		{name: "missing file"},
+		{name: "empty file", exists: true},
+		{name: "invalid file", exists: true, content: "{not json", wantErr: true},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, fmt.Sprintf("checkpoint-%d", i))
+			if tt.exists {
+				if err := os.WriteFile(path, []byte(tt.content), 0666); err != nil {
+					t.Fatalf("WriteFile returned an error: %v", err)
+				}
+			}
+
+			checkpoint, err := LoadCheckpoint(path)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("LoadCheckpoint() returned no error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadCheckpoint() returned an error: %v", err)
+			}
+
+			if _, ok := checkpoint.Resume(); ok {
+				t.Errorf("Resume() of an empty checkpoint returned a position")
+			}
+
+			// Nothing was recorded, the checkpoint is not written
+			if err := checkpoint.Save(); err != nil {
+				t.Fatalf("Save() returned an error: %v", err)
+			}
+			if _, err := os.Stat(path); !tt.exists && err == nil {
+				t.Errorf("Save() wrote a checkpoint without recorded entries")
+			}
+		})
+	}
+}
+
+func TestCheckpointSaveAndResume(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.checkpoint")
+	base := time.Date(2026, 10, 15, 9, 30, 0, 0, time.UTC)
+
+	checkpoint, err := LoadCheckpoint(path)
+	if err != nil {
+		t.Fatalf("LoadCheckpoint() returned an error: %v", err)
+	}
+
+	// Entries are recorded as printed, not in timestamp order
+	printed := []*Entry{
+		{InsertID: "b", Timestamp: base.Add(2 * time.Second)},
+		{InsertID: "a", Timestamp: base.Add(time.Second)},
+		{InsertID: "c", Timestamp: base.Add(3 * time.Second)},
+	}
+	for _, entry := range printed {
+		if err := checkpoint.record(entry); err != nil {
+			t.Fatalf("record() returned an error: %v", err)
+		}
+	}
+	if err := checkpoint.Save(); err != nil {
+		t.Fatalf("Save() returned an error: %v", err)
+	}
+
+	// The temporary file was renamed over the checkpoint
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir returned an error: %v", err)
+	}
+	if len(files) != 1 || files[0].Name() != "app.checkpoint" {
+		t.Errorf("checkpoint directory holds %v, want only app.checkpoint", files)
+	}
+
+	loaded, err := LoadCheckpoint(path)
+	if err != nil {
+		t.Fatalf("LoadCheckpoint() returned an error: %v", err)
+	}
+
+	if !loaded.Timestamp.Equal(base.Add(3 * time.Second)) {
+		t.Errorf("Timestamp = %v, want the newest entry %v", loaded.Timestamp, base.Add(3*time.Second))
+	}
+
+	resume, ok := loaded.Resume()
+	if !ok || !resume.Equal(base.Add(time.Second)) {
+		t.Errorf("Resume() = %v, %v, want the oldest recent entry %v", resume, ok, base.Add(time.Second))
+	}
+
+	// The entries of the previous session are skipped, new entries are printed and recorded
+	var got []string
+	printer := checkpointPrinter(printerFunc(func(entry *Entry) error {
+		got = append(got, entry.InsertID)
+		return nil
+	}), loaded)
+
+	entries := []*Entry{
+		{InsertID: "a", Timestamp: base.Add(time.Second)},
+		{InsertID: "b", Timestamp: base.Add(2 * time.Second)},
+		{InsertID: "b", Timestamp: base.Add(4 * time.Second)},
+		{InsertID: "c", Timestamp: base.Add(3 * time.Second)},
+		{InsertID: "d", Timestamp: base.Add(5 * time.Second)},
+		{Timestamp: base.Add(5 * time.Second)},
+	}
+	for _, entry := range entries {
+		if err := printer.Print(entry); err != nil {
+			t.Fatalf("Print() returned an error: %v", err)
+		}
+	}
+
+	want := []string{"b", "d", ""}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("printed %q, want %q", got, want)
+	}
+	if len(loaded.Recent) != len(printed)+len(want) {
+		t.Errorf("len(Recent) = %d, want %d", len(loaded.Recent), len(printed)+len(want))
+	}
+}
+
+func TestCheckpointRecordPrunes(t *testing.T) {
+	checkpoint, err := LoadCheckpoint(filepath.Join(t.TempDir(), "app.checkpoint"))
+	if err != nil {
+		t.Fatalf("LoadCheckpoint() returned an error: %v", err)
+	}
+
+	base := time.Date(2026, 10, 15, 9, 30, 0, 0, time.UTC)
+	for i := range checkpointSize + 5 {
+		entry := &Entry{InsertID: fmt.Sprintf("id-%d", i), Timestamp: base.Add(time.Duration(i) * time.Millisecond)}
+		if err := checkpoint.record(entry); err != nil {
+			t.Fatalf("record() returned an error: %v", err)
+		}
+	}
+
+	if len(checkpoint.Recent) != checkpointSize {
+		t.Fatalf("len(Recent) = %d, want %d", len(checkpoint.Recent), checkpointSize)
+	}
+	if first := checkpoint.Recent[0].InsertID; first != "id-5" {
+		t.Errorf("oldest recent entry = %s, want id-5", first)
+	}
+	if last := checkpoint.Recent[checkpointSize-1].InsertID; last != fmt.Sprintf("id-%d", checkpointSize+4) {
+		t.Errorf("newest recent entry = %s, want id-%d", last, checkpointSize+4)
+	}
+
+	resume, _ := checkpoint.Resume()
+	if want := base.Add(5 * time.Millisecond); !resume.Equal(want) {
+		t.Errorf("Resume() = %v, want %v", resume, want)
+	}
+}
@@ -0,0 +1,125 @@
+package stream
+
+import (
+	"strings"
+	"time"
+
+	"cloud.google.com/go/logging/apiv2/loggingpb"
+)
+
+// Entry is the normalized log entry shared by the history (ListLogEntries) and streaming (TailLogEntries) paths
+type Entry struct {
+	InsertID       string
+	LogName        string
+	Timestamp      time.Time
+	Severity       string
+	Resource       Resource
+	Labels         map[string]string
+	Trace          string
+	SpanID         string
+	SourceLocation *SourceLocation
+	HTTPRequest    *HTTPRequest
+	TextPayload    string
+	JSONPayload    map[string]any
+	ProtoPayload   map[string]any
+	// Late is set on streamed entries released after newer ones because they arrived beyond the reorder window
+	Late bool
+}
+
+// Resource is the monitored resource that produced an entry
+type Resource struct {
+	Type   string
+	Labels map[string]string
+}
+
+// SourceLocation is the source code location that produced an entry
+type SourceLocation struct {
+	File     string
+	Line     int64
+	Function string
+}
+
+// HTTPRequest holds the HTTP request information attached to an entry
+type HTTPRequest struct {
+	Method       string
+	URL          string
+	Status       int
+	Latency      time.Duration
+	RequestSize  int64
+	ResponseSize int64
+	RemoteIP     string
+	UserAgent    string
+	Referer      string
+}
+
+// Message returns the main human-readable message of the entry, derived from its payload
+func (e *Entry) Message() string {
+	switch {
+	case e.TextPayload != "":
+		return strings.TrimSpace(e.TextPayload)
+	case e.JSONPayload != nil:
+		return formatJSONPayload(e.JSONPayload)
+	case e.ProtoPayload != nil:
+		return formatProtoPayload(e.ProtoPayload)
+	}
+
+	return ""
+}
+
+// Project returns the ID of the project the entry was written to, derived from its log name.
+// Entries written to an organization, folder or billing account return its resource name (e.g. "organizations/123").
+func (e *Entry) Project() string {
+	parent, _, _ := strings.Cut(e.LogName, "/logs/")
+
+	if project, found := strings.CutPrefix(parent, "projects/"); found {
+		return project
+	}
+
+	return parent
+}
+
+// entryFromProto converts an entry listed by ListLogEntries or received from the TailLogEntries stream
+func entryFromProto(entry *loggingpb.LogEntry) *Entry {
+	e := &Entry{
+		InsertID:  entry.GetInsertId(),
+		LogName:   entry.GetLogName(),
+		Timestamp: entry.GetTimestamp().AsTime(),
+		Severity:  entry.GetSeverity().String(),
+		Labels:    entry.GetLabels(),
+		Trace:     entry.GetTrace(),
+		SpanID:    entry.GetSpanId(),
+	}
+
+	if res := entry.GetResource(); res != nil {
+		e.Resource = Resource{Type: res.GetType(), Labels: res.GetLabels()}
+	}
+
+	if loc := entry.GetSourceLocation(); loc != nil {
+		e.SourceLocation = &SourceLocation{File: loc.GetFile(), Line: loc.GetLine(), Function: loc.GetFunction()}
+	}
+
+	if req := entry.GetHttpRequest(); req != nil {
+		e.HTTPRequest = &HTTPRequest{
+			Method:       req.GetRequestMethod(),
+			URL:          req.GetRequestUrl(),
+			Status:       int(req.GetStatus()),
+			Latency:      req.GetLatency().AsDuration(),
+			RequestSize:  req.GetRequestSize(),
+			ResponseSize: req.GetResponseSize(),
+			RemoteIP:     req.GetRemoteIp(),
+			UserAgent:    req.GetUserAgent(),
+			Referer:      req.GetReferer(),
+		}
+	}
+
+	switch {
+	case entry.GetTextPayload() != "":
+		e.TextPayload = entry.GetTextPayload()
+	case entry.GetJsonPayload() != nil:
+		e.JSONPayload = entry.GetJsonPayload().AsMap()
+	case entry.GetProtoPayload() != nil:
+		e.ProtoPayload = protoPayloadFields(entry.GetProtoPayload())
+	}
+
+	return e
+}
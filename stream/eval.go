@@ -0,0 +1,344 @@
+package stream
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Match reports whether an entry matches the query, evaluated locally on the fields of its JSON representation
+// (timestamp, severity, logName, resource.labels.*, jsonPayload.*, etc.). Severities are ordered by level,
+// timestamps by time and numbers by value. Functions other than SEARCH and log_id cannot be evaluated locally.
+func (q *Query) Match(entry *Entry) (bool, error) {
+	if q.root == nil {
+		return true, nil
+	}
+
+	fields, err := recordFields(newRecord(entry))
+	if err != nil {
+		return false, err
+	}
+
+	e := &evaluator{fields: fields, regexps: q.regexps()}
+	return e.eval(q.root)
+}
+
+// regexps returns the cache of the regular expressions compiled for the query
+func (q *Query) regexps() map[string]*regexp.Regexp {
+	if q.compiled == nil {
+		q.compiled = make(map[string]*regexp.Regexp)
+	}
+
+	return q.compiled
+}
+
+// evaluator evaluates an expression against the fields of an entry
+type evaluator struct {
+	fields  map[string]any
+	regexps map[string]*regexp.Regexp
+}
+
+func (e *evaluator) eval(node expr) (bool, error) {
+	switch n := node.(type) {
+	case and:
+		for _, child := range n {
+			matched, err := e.eval(child)
+			if err != nil || !matched {
+				return false, err
+			}
+		}
+		return true, nil
+
+	case or:
+		for _, child := range n {
+			matched, err := e.eval(child)
+			if err != nil || matched {
+				return matched, err
+			}
+		}
+		return false, nil
+
+	case not:
+		matched, err := e.eval(n.expr)
+		return !matched, err
+
+	case group:
+		return e.eval(n.expr)
+
+	case userExpr:
+		query, err := ParseQuery(string(n))
+		if err != nil {
+			return false, err
+		}
+		return e.eval(query.root)
+
+	case comparison:
+		return e.compare(n)
+
+	case has:
+		values, ok := e.lookup(n.field)
+		return ok && anyContains(values, n.value), nil
+
+	case exists:
+		_, ok := e.lookup(n.field)
+		return ok, nil
+
+	case globalTerm:
+		return anyContains(e.fields, n.value), nil
+
+	case call:
+		args := make([]funcArg, 0, len(n.args))
+		for _, arg := range n.args {
+			args = append(args, funcArg{value: arg, quoted: true})
+		}
+		return e.call(funcCall{name: n.name, args: args})
+
+	case funcCall:
+		return e.call(n)
+	}
+
+	return false, fmt.Errorf("cannot evaluate expression %s", node)
+}
+
+// lookup returns the value of a field path, e.g. labels."k8s-pod/app"
+func (e *evaluator) lookup(path string) (any, bool) {
+	value, ok := lookupField(e.fields, splitFieldPath(path)...)
+	return value, ok && value != nil
+}
+
+// compare evaluates a comparison, any element of a repeated field may match.
+// Negated operators (!= and !~) match when no element matches, including when the field is missing.
+func (e *evaluator) compare(c comparison) (bool, error) {
+	negated := c.op == opNotEquals || c.op == opNotRegex
+
+	value, ok := e.lookup(c.field)
+	if !ok {
+		return negated, nil
+	}
+
+	values, repeated := value.([]any)
+	if !repeated {
+		values = []any{value}
+	}
+
+	for _, v := range values {
+		matched, err := e.compareValue(c, v)
+		if err != nil {
+			return false, err
+		}
+		if matched {
+			return !negated, nil
+		}
+	}
+
+	return negated, nil
+}
+
+// compareValue compares a single field value, negated operators are evaluated as their positive form
+func (e *evaluator) compareValue(c comparison, value any) (bool, error) {
+	// Objects are neither equal nor ordered relative to a value
+	switch value.(type) {
+	case map[string]any, []any:
+		return false, nil
+	}
+
+	text := fieldText(value)
+
+	switch c.op {
+	case opRegex, opNotRegex:
+		re, err := e.regexp(c.value)
+		if err != nil {
+			return false, err
+		}
+		return re.MatchString(text), nil
+	}
+
+	order := compareOrdered(c.field, text, c.value)
+
+	switch c.op {
+	case opEquals, opNotEquals:
+		return order == 0, nil
+	case opLess:
+		return order < 0, nil
+	case opLessEq:
+		return order <= 0, nil
+	case opGreater:
+		return order > 0, nil
+	case opGreaterEq:
+		return order >= 0, nil
+	}
+
+	return false, fmt.Errorf("unknown operator %q", c.op)
+}
+
+// compareOrdered compares a field value with the value of a comparison: severities by level,
+// timestamps by time, numbers by value and other values as strings
+func compareOrdered(path, text, value string) int {
+	switch path {
+	case "severity":
+		if level, ok := querySeverityLevel(value); ok {
+			return compareInts(SeverityLevel(text), level)
+		}
+
+	case "timestamp":
+		left, errLeft := time.Parse(time.RFC3339Nano, text)
+		right, errRight := time.Parse(time.RFC3339Nano, value)
+		if errLeft == nil && errRight == nil {
+			return left.Compare(right)
+		}
+	}
+
+	left, errLeft := strconv.ParseFloat(text, 64)
+	right, errRight := strconv.ParseFloat(value, 64)
+	if errLeft == nil && errRight == nil {
+		switch {
+		case left < right:
+			return -1
+		case left > right:
+			return 1
+		default:
+			return 0
+		}
+	}
+
+	return strings.Compare(text, value)
+}
+
+// querySeverityLevel returns the level of a severity in a query, a name such as ERROR or a number such as 500.
+// Unlike ParseSeverity, the aliases of the command line flags (e.g. err or warn) are not severities of the query language.
+func querySeverityLevel(value string) (int, bool) {
+	if level, ok := severityLevels[strings.ToUpper(value)]; ok {
+		return level, true
+	}
+
+	if level, err := strconv.Atoi(value); err == nil {
+		return level, true
+	}
+
+	return 0, false
+}
+
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// call evaluates the functions that can be evaluated locally
+func (e *evaluator) call(c funcCall) (bool, error) {
+	switch strings.ToUpper(c.name) {
+	case "SEARCH":
+		// SEARCH(text) or SEARCH(field, text), a backquoted text is searched as a phrase
+		var scope any = e.fields
+		if len(c.args) == 2 {
+			value, ok := e.lookup(c.args[0].value)
+			if !ok {
+				return false, nil
+			}
+			scope = value
+		}
+
+		if len(c.args) == 0 || len(c.args) > 2 {
+			return false, fmt.Errorf("SEARCH expects 1 or 2 arguments, got %d", len(c.args))
+		}
+
+		text := strings.Trim(c.args[len(c.args)-1].value, "`")
+		return anyContains(scope, text), nil
+
+	case "LOG_ID":
+		if len(c.args) != 1 {
+			return false, fmt.Errorf("log_id expects 1 argument, got %d", len(c.args))
+		}
+
+		logName, _ := e.fields["logName"].(string)
+		_, logID, found := strings.Cut(logName, "/logs/")
+		return found && (logID == c.args[0].value || logID == strings.ReplaceAll(c.args[0].value, "/", "%2F")), nil
+	}
+
+	return false, fmt.Errorf("function %s cannot be evaluated locally", c.name)
+}
+
+// regexp returns the compiled regular expression, compiling it once per query
+func (e *evaluator) regexp(pattern string) (*regexp.Regexp, error) {
+	if re, ok := e.regexps[pattern]; ok {
+		return re, nil
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regular expression %q: \n%w", pattern, err)
+	}
+	e.regexps[pattern] = re
+
+	return re, nil
+}
+
+// anyContains reports whether a value, or any value nested in it, contains text case-insensitively
+func anyContains(value any, text string) bool {
+	switch v := value.(type) {
+	case map[string]any:
+		for _, child := range v {
+			if anyContains(child, text) {
+				return true
+			}
+		}
+		return false
+	case []any:
+		for _, child := range v {
+			if anyContains(child, text) {
+				return true
+			}
+		}
+		return false
+	case nil:
+		return false
+	}
+
+	return strings.Contains(strings.ToLower(fieldText(value)), strings.ToLower(text))
+}
+
+// fieldText renders a scalar field value as text, e.g. 200 instead of 200.0 for numbers
+func fieldText(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case map[string]any, []any:
+		return ""
+	}
+
+	return fmt.Sprint(value)
+}
+
+// splitFieldPath splits a field path on the dots that are not inside quoted segments,
+// e.g. labels."compute.googleapis.com/resource_name" gives labels and compute.googleapis.com/resource_name
+func splitFieldPath(path string) []string {
+	var segments []string
+	var segment strings.Builder
+
+	quoted := false
+	for i := 0; i < len(path); i++ {
+		switch c := path[i]; {
+		case c == '\\' && quoted && i+1 < len(path):
+			i++
+			segment.WriteByte(path[i])
+		case c == '"':
+			quoted = !quoted
+		case c == '.' && !quoted:
+			segments = append(segments, segment.String())
+			segment.Reset()
+		default:
+			segment.WriteByte(c)
+		}
+	}
+
+	return append(segments, segment.String())
+}
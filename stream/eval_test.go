@@ -0,0 +1,149 @@
+package stream
+
+import (
+	"testing"
+	"time"
+)
+
+// evalEntry is the entry the queries of TestQueryMatch are evaluated against
+func evalEntry() *Entry {
+	return &Entry{
+		InsertID:  "abc123",
+		LogName:   "projects/p/logs/cloudaudit.googleapis.com%2Factivity",
+		Timestamp: time.Date(2026, 10, 15, 9, 30, 0, 500_000_000, time.UTC),
+		Severity:  "ERROR",
+		Resource: Resource{
+			Type:   "k8s_container",
+			Labels: map[string]string{"namespace_name": "payments", "pod_name": "api-7f9c"},
+		},
+		Labels: map[string]string{"env": "prod", "k8s-pod/app": "api"},
+		JSONPayload: map[string]any{
+			"message": "upstream timeout after 30s",
+			"status":  503,
+			"attempt": 3,
+			"tags":    []any{"retry", "upstream"},
+			"user":    map[string]any{"id": "u-42"},
+		},
+	}
+}
+
+func TestQueryMatch(t *testing.T) {
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{query: "", want: true},
+
+		// Severities are ordered by level
+		{query: `severity="ERROR"`, want: true},
+		{query: `severity=error`, want: true},
+		{query: `severity>=WARNING`, want: true},
+		{query: `severity>ERROR`, want: false},
+		{query: `severity<CRITICAL`, want: true},
+		{query: `severity<=DEBUG`, want: false},
+		{query: `severity>=400`, want: true},
+		{query: `severity=err`, want: false},
+		{query: `severity=warn`, want: false},
+
+		// Timestamps are compared as times, whatever their offset
+		{query: `timestamp>="2026-10-15T09:00:00Z" AND timestamp<"2026-10-15T10:00:00Z"`, want: true},
+		{query: `timestamp>"2026-10-15T09:30:00.6Z"`, want: false},
+		{query: `timestamp="2026-10-15T11:30:00.5+02:00"`, want: true},
+		{query: `timestamp<"2026-10-15T09:30:00Z"`, want: false},
+
+		// Regular expressions
+		{query: `jsonPayload.message=~"timeout after \d+s"`, want: true},
+		{query: `jsonPayload.message=~"^timeout"`, want: false},
+		{query: `jsonPayload.message!~"^upstream"`, want: false},
+		{query: `jsonPayload.message!~"^downstream"`, want: true},
+		{query: `resource.labels.pod_name=~"^api-[0-9a-f]+$"`, want: true},
+
+		// Has and exists
+		{query: `jsonPayload.message:"TIMEOUT"`, want: true},
+		{query: `jsonPayload.message:"deadline"`, want: false},
+		{query: `resource.labels.pod_name:"api-"`, want: true},
+		{query: `jsonPayload.user:"u-42"`, want: true},
+		{query: `labels.env:*`, want: true},
+		{query: `jsonPayload.user:*`, want: true},
+		{query: `labels.team:*`, want: false},
+		{query: `labels."k8s-pod/app"="api"`, want: true},
+
+		// Numbers are compared by value
+		{query: `jsonPayload.status>=500`, want: true},
+		{query: `jsonPayload.status<=99`, want: false},
+		{query: `jsonPayload.attempt=3`, want: true},
+
+		// Any element of a repeated field may match
+		{query: `jsonPayload.tags="retry"`, want: true},
+		{query: `jsonPayload.tags="upstream"`, want: true},
+		{query: `jsonPayload.tags="failed"`, want: false},
+		{query: `jsonPayload.tags!="retry"`, want: false},
+
+		// Negated comparisons match missing fields
+		{query: `labels.team!="payments"`, want: true},
+		{query: `labels.team="payments"`, want: false},
+		{query: `labels.team!~"pay"`, want: true},
+		{query: `labels.team=~"pay"`, want: false},
+
+		// NOT binds tighter than OR, and OR tighter than AND
+		{query: `NOT severity="ERROR" OR labels.env="prod"`, want: true},
+		{query: `NOT (severity="ERROR" OR labels.env="prod")`, want: false},
+		{query: `labels.env="prod" OR labels.env="dev" AND severity="DEBUG"`, want: false},
+		{query: `-labels.env="dev"`, want: true},
+		{query: `severity="ERROR" labels.env="dev"`, want: false},
+
+		// Bare values are searched in all fields
+		{query: `timeout`, want: true},
+		{query: `"connection reset"`, want: false},
+
+		// Functions
+		{query: `SEARCH("timeout")`, want: true},
+		{query: "SEARCH(\"`upstream timeout`\")", want: true},
+		{query: `SEARCH(jsonPayload.message, "upstream")`, want: true},
+		{query: `SEARCH(textPayload, "upstream")`, want: false},
+		{query: `SEARCH("deadline")`, want: false},
+		{query: `log_id("cloudaudit.googleapis.com/activity")`, want: true},
+		{query: `log_id("stdout")`, want: false},
+	}
+
+	entry := evalEntry()
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			query, err := ParseQuery(tt.query)
+			if err != nil {
+				t.Fatalf("ParseQuery(%q) returned an error: %v", tt.query, err)
+			}
+
+			got, err := query.Match(entry)
+			if err != nil {
+				t.Fatalf("Match(%q) returned an error: %v", tt.query, err)
+			}
+			if got != tt.want {
+				t.Errorf("Match(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueryMatchErrors(t *testing.T) {
+	queries := []string{
+		`sample(insertId, 0.25)`,
+		`jsonPayload.message=~"("`,
+		`SEARCH()`,
+		`log_id("a", "b")`,
+	}
+
+	entry := evalEntry()
+	for _, text := range queries {
+		t.Run(text, func(t *testing.T) {
+			query, err := ParseQuery(text)
+			if err != nil {
+				t.Fatalf("ParseQuery(%q) returned an error: %v", text, err)
+			}
+
+			if _, err := query.Match(entry); err == nil {
+				t.Errorf("Match(%q) returned no error", text)
+			}
+		})
+	}
+}
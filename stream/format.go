@@ -1,56 +1,230 @@
 package stream
 
 import (
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
+	"hash/fnv"
 	"os"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"golang.org/x/term"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	// Register the protoPayload types most commonly found in Cloud Logging so they can be unpacked
+	_ "google.golang.org/genproto/googleapis/appengine/logging/v1"
+	_ "google.golang.org/genproto/googleapis/cloud/audit"
 )
 
 type Filter struct {
 	LogName      string
 	ResourceType string
-	Severity     string
+	Severities   []string // entries matching any of these severities
+	MinSeverity  string
+	MaxSeverity  string
 	Since        time.Duration
 	SinceTime    time.Time
+	Until        time.Duration // entries older than this duration
+	UntilTime    time.Time
 	CustomFilter string
+
+	// Search matches entries containing a text, with SEARCH() over all fields or the "has" operator over SearchFields
+	Search       string
+	SearchFields []string // e.g. textPayload and jsonPayload.message, empty to search all fields
+
+	// Kubernetes shortcuts, any of them restricts the query to k8s_container resources
+	Cluster   string
+	Namespace string
+	Pod       string // pod name prefix, or a regular expression when it contains regex metacharacters
+	Container string
+	Location  string
+
+	// Selector holds the label selector requirements, matched against entry labels and resource labels
+	Selector []LabelRequirement
+
+	// PresetValues holds the values of the resource preset flags, keyed by flag name (e.g. "run-service")
+	PresetValues map[string]string
 }
 
-func BuildFilterString(filter *Filter) string {
-	var options []string
+// TimeWindow returns the start and end of the time window selected by the filter relative to now.
+// A zero start or end means the window is unbounded on that side.
+func (f *Filter) TimeWindow(now time.Time) (start time.Time, end time.Time) {
+	if f.Since != 0 {
+		start = now.Add(-f.Since)
+	}
+	if !f.SinceTime.IsZero() {
+		start = f.SinceTime
+	}
+
+	if f.Until != 0 {
+		end = now.Add(-f.Until)
+	}
+	if !f.UntilTime.IsZero() {
+		end = f.UntilTime
+	}
 
+	return start, end
+}
+
+// HasKubernetes reports whether any of the Kubernetes shortcuts is set
+func (f *Filter) HasKubernetes() bool {
+	return f.Cluster != "" || f.Namespace != "" || f.Pod != "" || f.Container != "" || f.Location != ""
+}
+
+// BuildFilterString renders a filter as a logging query. Values are quoted and escaped,
+// and the custom filter is parenthesized so that it does not change the meaning of the other clauses.
+func BuildFilterString(filter *Filter) string {
 	if filter == nil {
 		return ""
 	}
 
+	return filterExpr(filter).String()
+}
+
+// filterExpr builds the expression selecting the entries matching the filter
+func filterExpr(filter *Filter) and {
+	var clauses and
+
 	if filter.LogName != "" {
-		options = append(options, fmt.Sprintf(`logName = "%s"`, filter.LogName))
+		clauses = append(clauses, comparison{"logName", opEquals, filter.LogName})
 	}
 
 	if filter.ResourceType != "" {
-		options = append(options, fmt.Sprintf(`resource.type = "%s"`, filter.ResourceType))
+		clauses = append(clauses, comparison{"resource.type", opEquals, filter.ResourceType})
+	} else if filter.HasKubernetes() {
+		clauses = append(clauses, comparison{"resource.type", opEquals, "k8s_container"})
+	}
+
+	if filter.Cluster != "" {
+		clauses = append(clauses, comparison{"resource.labels.cluster_name", opEquals, filter.Cluster})
+	}
+
+	if filter.Location != "" {
+		clauses = append(clauses, comparison{"resource.labels.location", opEquals, filter.Location})
+	}
+
+	if filter.Namespace != "" {
+		clauses = append(clauses, comparison{"resource.labels.namespace_name", opEquals, filter.Namespace})
+	}
+
+	if filter.Pod != "" {
+		clauses = append(clauses, comparison{"resource.labels.pod_name", opRegex, podNamePattern(filter.Pod)})
+	}
+
+	if filter.Container != "" {
+		clauses = append(clauses, comparison{"resource.labels.container_name", opEquals, filter.Container})
+	}
+
+	if preset, err := ActivePreset(filter.PresetValues); err == nil && preset != nil {
+		for _, clause := range preset.exprs(filter.PresetValues) {
+			// The preset resource type may already be selected by --resource-type
+			if !slices.ContainsFunc(clauses, func(e expr) bool { return e.String() == clause.String() }) {
+				clauses = append(clauses, clause)
+			}
+		}
+	}
+
+	for _, requirement := range filter.Selector {
+		clauses = append(clauses, requirement.expr())
+	}
+
+	if len(filter.Severities) > 0 {
+		var severities or
+		for _, severity := range filter.Severities {
+			severities = append(severities, comparison{"severity", opEquals, severity})
+		}
+		clauses = append(clauses, severities)
 	}
 
-	if filter.Severity != "" {
-		options = append(options, fmt.Sprintf(`severity = "%s"`, filter.Severity))
+	if filter.MinSeverity != "" {
+		clauses = append(clauses, comparison{"severity", opGreaterEq, filter.MinSeverity})
+	}
+
+	if filter.MaxSeverity != "" {
+		clauses = append(clauses, comparison{"severity", opLessEq, filter.MaxSeverity})
 	}
 
 	if filter.Since != 0 {
 		sinceTime := time.Now().Add(-filter.Since).Format(time.RFC3339)
-		options = append(options, fmt.Sprintf(`timestamp >= "%s"`, sinceTime))
+		clauses = append(clauses, comparison{"timestamp", opGreaterEq, sinceTime})
 	}
 
 	if !filter.SinceTime.IsZero() {
-		options = append(options, fmt.Sprintf(`timestamp >= "%s"`, filter.SinceTime.Format(time.RFC3339)))
+		// Fractional seconds are kept, a checkpoint resumes from the exact timestamp of an entry
+		clauses = append(clauses, comparison{"timestamp", opGreaterEq, filter.SinceTime.Format(time.RFC3339Nano)})
+	}
+
+	if filter.Until != 0 {
+		untilTime := time.Now().Add(-filter.Until).Format(time.RFC3339)
+		clauses = append(clauses, comparison{"timestamp", opLessEq, untilTime})
+	}
+
+	if !filter.UntilTime.IsZero() {
+		clauses = append(clauses, comparison{"timestamp", opLessEq, filter.UntilTime.Format(time.RFC3339)})
+	}
+
+	// Any timestamp clause disables the default 24 hours lookback of the history,
+	// so a window with only an end looks back 24 hours from that end
+	if start, end := filter.TimeWindow(time.Now()); start.IsZero() && !end.IsZero() {
+		clauses = append(clauses, comparison{"timestamp", opGreaterEq, end.Add(-24 * time.Hour).Format(time.RFC3339)})
+	}
+
+	if filter.Search != "" {
+		clauses = append(clauses, searchExpr(filter.Search, filter.SearchFields))
 	}
 
-	if filter.CustomFilter != "" {
-		options = append(options, fmt.Sprintf(`%s`, filter.CustomFilter))
+	// The custom filter is parenthesized so that its OR operators do not apply to the other clauses.
+	// A filter made only of comments selects nothing more, and would leave empty parentheses.
+	if custom := strings.TrimSpace(filter.CustomFilter); custom != "" && !isEmptyQuery(custom) {
+		clauses = append(clauses, userExpr(custom))
 	}
 
-	return strings.Join(options, " AND ")
+	return clauses
+}
+
+// searchExpr returns the expression matching entries containing text. Without fields, SEARCH() looks in all fields
+// and multi-word texts are searched as an exact phrase, unless they contain a backtick. With fields, any of the
+// fields must contain text.
+func searchExpr(text string, fields []string) expr {
+	if len(fields) == 0 {
+		if strings.ContainsAny(text, " \t") && !strings.Contains(text, "`") {
+			text = "`" + text + "`"
+		}
+		return call{"SEARCH", []string{text}}
+	}
+
+	var matches or
+	for _, f := range fields {
+		matches = append(matches, has{field(strings.Split(f, ".")...), text})
+	}
+
+	return matches
+}
+
+// podNamePattern returns the regular expression matching a --pod value.
+// Plain names (letters, digits, "-" and ".") are matched as a prefix, anything else is used as a regular expression.
+func podNamePattern(pod string) string {
+	isPlain := strings.IndexFunc(pod, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '.')
+	}) == -1
+
+	if !isPlain {
+		return pod
+	}
+
+	// Dots are matched literally with a character class, which avoids backslash escapes in the query string
+	return "^" + strings.ReplaceAll(pod, ".", "[.]")
 }
 
 func formatSeverity(severity string) string {
@@ -81,3 +255,262 @@ func formatSeverity(severity string) string {
 
 	return upper
 }
+
+// projectColors are the colors given to project prefixes, a project always gets the same color
+var projectColors = []string{
+	"\033[36m", // Cyan
+	"\033[35m", // Magenta
+	"\033[32m", // Green
+	"\033[33m", // Yellow
+	"\033[34m", // Blue
+	"\033[96m", // Bright cyan
+	"\033[95m", // Bright magenta
+	"\033[92m", // Bright green
+}
+
+// formatProject renders the "[project]" line prefix, colored when stdout is a terminal
+func formatProject(project string) string {
+	prefix := "[" + project + "]"
+
+	if !term.IsTerminal(int(os.Stdout.Fd())) {
+		return prefix
+	}
+
+	hash := fnv.New32a()
+	hash.Write([]byte(project))
+	color := projectColors[hash.Sum32()%uint32(len(projectColors))]
+
+	return fmt.Sprintf("%s%s\033[0m", color, prefix)
+}
+
+// messageKeys lists the jsonPayload keys commonly used by logging libraries for the main message, by priority
+var messageKeys = []string{"message", "msg", "log", "textPayload", "text"}
+
+// jsonPayloadMessage returns the key and the value of the main message of a jsonPayload, empty when it has none
+func jsonPayloadMessage(fields map[string]any) (string, string) {
+	for _, key := range messageKeys {
+		value, ok := fields[key].(string)
+		if ok && strings.TrimSpace(value) != "" {
+			return key, strings.TrimSpace(value)
+		}
+	}
+
+	return "", ""
+}
+
+// formatJSONPayload renders a jsonPayload as its main message followed by the other fields as compact key=value pairs
+func formatJSONPayload(fields map[string]any) string {
+	messageKey, message := jsonPayloadMessage(fields)
+
+	rest := make(map[string]any, len(fields))
+	for key, value := range fields {
+		if key != messageKey || message == "" {
+			rest[key] = value
+		}
+	}
+
+	keys := make([]string, 0, len(rest))
+	for key := range rest {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys)+1)
+	if message != "" {
+		parts = append(parts, message)
+	}
+	for _, key := range keys {
+		parts = append(parts, fmt.Sprintf("%s=%s", key, formatFieldValue(rest[key])))
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// formatFieldValue renders a single jsonPayload value, quoting strings containing spaces and encoding nested values as compact JSON
+func formatFieldValue(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		if v == "" || strings.ContainsAny(v, " \t\n\"=") {
+			return strconv.Quote(v)
+		}
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(encoded)
+	}
+}
+
+// protoPayloadFields unpacks a protoPayload into its JSON fields including "@type".
+// Nested Any fields of an unknown type (e.g. the serviceData of legacy BigQuery audit logs) only keep their "@type".
+// When the type of the payload itself is unknown, the packed message is kept as base64 under "value".
+func protoPayloadFields(payload *anypb.Any) map[string]any {
+	var fields map[string]any
+
+	if _, err := protoregistry.GlobalTypes.FindMessageByURL(payload.GetTypeUrl()); err == nil {
+		encoded, err := protojson.MarshalOptions{Resolver: nestedTypeResolver{protoregistry.GlobalTypes}}.Marshal(payload)
+		if err == nil && json.Unmarshal(encoded, &fields) == nil {
+			return fields
+		}
+	}
+
+	return map[string]any{
+		"@type": payload.GetTypeUrl(),
+		"value": base64.StdEncoding.EncodeToString(payload.GetValue()),
+	}
+}
+
+// nestedTypeResolver resolves the registered types, and resolves the unknown ones to an empty message
+// so that an Any of an unknown type nested in a payload does not fail the encoding of the whole payload
+type nestedTypeResolver struct {
+	*protoregistry.Types
+}
+
+func (r nestedTypeResolver) FindMessageByURL(url string) (protoreflect.MessageType, error) {
+	if messageType, err := r.Types.FindMessageByURL(url); err == nil {
+		return messageType, nil
+	}
+
+	return unknownMessageType, nil
+}
+
+// unknownMessageType is an empty message, the fields of the messages decoded as this type are discarded
+var unknownMessageType = func() protoreflect.MessageType {
+	file, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:        proto.String("cloudtail/unknown.proto"),
+		Package:     proto.String("cloudtail"),
+		MessageType: []*descriptorpb.DescriptorProto{{Name: proto.String("Unknown")}},
+	}, nil)
+	if err != nil {
+		panic(err)
+	}
+
+	return dynamicpb.NewMessageType(file.Messages().Get(0))
+}()
+
+// formatProtoPayload renders a protoPayload in a compact form when its type is known,
+// otherwise it shows the type URL followed by the raw JSON of the packed message
+func formatProtoPayload(payload map[string]any) string {
+	typeURL, _ := payload["@type"].(string)
+
+	raw := func() string {
+		encoded, _ := json.Marshal(payload)
+		return fmt.Sprintf("%s %s", typeURL, encoded)
+	}
+
+	if _, err := protoregistry.GlobalTypes.FindMessageByURL(typeURL); err != nil {
+		return raw()
+	}
+
+	fields := make(map[string]any, len(payload))
+	for key, value := range payload {
+		if key != "@type" {
+			fields[key] = value
+		}
+	}
+
+	typeName := typeURL[strings.LastIndex(typeURL, "/")+1:]
+
+	var compact string
+	switch typeName {
+	case "google.cloud.audit.AuditLog":
+		compact = formatAuditLog(fields)
+	case "google.appengine.logging.v1.RequestLog":
+		compact = formatRequestLog(fields)
+	default:
+		compact = strings.TrimSpace(fmt.Sprintf("%s %s", typeName, formatJSONPayload(fields)))
+	}
+
+	// A payload that could not be decoded has none of the summarized fields, it is shown raw rather than dropped
+	if compact == "" {
+		return raw()
+	}
+
+	return compact
+}
+
+// formatAuditLog renders a Cloud Audit Logs entry as "method resource by principal" with its status when not OK
+func formatAuditLog(fields map[string]any) string {
+	parts := []string{lookupString(fields, "serviceName"), lookupString(fields, "methodName"), lookupString(fields, "resourceName")}
+
+	if principal := lookupString(fields, "authenticationInfo", "principalEmail"); principal != "" {
+		parts = append(parts, "by "+principal)
+	}
+
+	if code, ok := lookupField(fields, "status", "code"); ok {
+		parts = append(parts, fmt.Sprintf("status=%s", formatFieldValue(code)))
+		if message := lookupString(fields, "status", "message"); message != "" {
+			parts = append(parts, strconv.Quote(message))
+		}
+	}
+
+	return joinNonEmpty(parts)
+}
+
+// formatRequestLog renders an App Engine request log like an HTTP request line, followed by its application log lines
+func formatRequestLog(fields map[string]any) string {
+	parts := []string{lookupString(fields, "method"), lookupString(fields, "resource")}
+
+	if status, ok := lookupField(fields, "status"); ok {
+		parts = append(parts, formatFieldValue(status))
+	}
+	parts = append(parts, lookupString(fields, "latency"))
+
+	if lines, ok := fields["line"].([]any); ok {
+		for _, line := range lines {
+			if message := lookupString(map[string]any{"line": line}, "line", "logMessage"); message != "" {
+				parts = append(parts, "| "+message)
+			}
+		}
+	}
+
+	return joinNonEmpty(parts)
+}
+
+// lookupField returns the value found by following path through nested JSON objects
+func lookupField(fields map[string]any, path ...string) (any, bool) {
+	var current any = fields
+	for _, key := range path {
+		object, ok := current.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+
+		current, ok = object[key]
+		if !ok {
+			return nil, false
+		}
+	}
+
+	return current, true
+}
+
+// lookupString returns the string found by following path through nested JSON objects, or an empty string
+func lookupString(fields map[string]any, path ...string) string {
+	value, ok := lookupField(fields, path...)
+	if !ok {
+		return ""
+	}
+
+	str, _ := value.(string)
+	return str
+}
+
+func joinNonEmpty(parts []string) string {
+	nonEmpty := parts[:0]
+	for _, part := range parts {
+		if part != "" {
+			nonEmpty = append(nonEmpty, part)
+		}
+	}
+
+	return strings.Join(nonEmpty, " ")
+}
@@ -0,0 +1,200 @@
+package stream
+
+import (
+	"testing"
+	"time"
+
+	"google.golang.org/genproto/googleapis/cloud/audit"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+func TestBuildFilterString(t *testing.T) {
+	selector, err := ParseSelector("env=prod,tier!=cache,team in (payments,ledger),app.kubernetes.io/name,!canary")
+	if err != nil {
+		t.Fatalf("ParseSelector returned an error: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   string
+	}{
+		{
+			name:   "empty",
+			filter: Filter{},
+			want:   "",
+		},
+		{
+			name:   "quote and backslash in log name",
+			filter: Filter{LogName: `projects/p/logs/a"b\c`},
+			want:   `logName = "projects/p/logs/a\"b\\c"`,
+		},
+		{
+			name:   "OR filter with severities",
+			filter: Filter{CustomFilter: `severity=ERROR OR textPayload:"timeout"`, Severities: []string{"WARNING", "ERROR"}},
+			want:   "(severity = \"WARNING\" OR severity = \"ERROR\") AND (\nseverity=ERROR OR textPayload:\"timeout\"\n)",
+		},
+		{
+			name:   "OR filter with a minimum severity",
+			filter: Filter{CustomFilter: `resource.type="gce_instance" OR resource.type="gae_app"`, MinSeverity: "WARNING"},
+			want:   "severity >= \"WARNING\" AND (\nresource.type=\"gce_instance\" OR resource.type=\"gae_app\"\n)",
+		},
+		{
+			name:   "filter ending with a comment",
+			filter: Filter{CustomFilter: "severity>=ERROR -- only errors", MaxSeverity: "CRITICAL"},
+			want:   "severity <= \"CRITICAL\" AND (\nseverity>=ERROR -- only errors\n)",
+		},
+		{
+			name:   "filter made of comments",
+			filter: Filter{CustomFilter: "-- nothing to filter", ResourceType: "gce_instance"},
+			want:   `resource.type = "gce_instance"`,
+		},
+		{
+			name:   "selector",
+			filter: Filter{Selector: selector},
+			want: `(labels.env = "prod" OR resource.labels.env = "prod")` +
+				` AND NOT (labels.tier = "cache" OR resource.labels.tier = "cache")` +
+				` AND (labels.team = "payments" OR labels.team = "ledger" OR resource.labels.team = "payments" OR resource.labels.team = "ledger")` +
+				` AND (labels."app.kubernetes.io/name":* OR resource.labels."app.kubernetes.io/name":*)` +
+				` AND NOT (labels.canary:* OR resource.labels.canary:*)`,
+		},
+		{
+			name:   "Cloud Run preset",
+			filter: Filter{PresetValues: map[string]string{"run-service": "api", "revision": "api-00042"}},
+			want:   `resource.type = "cloud_run_revision" AND resource.labels.service_name = "api" AND resource.labels.revision_name = "api-00042"`,
+		},
+		{
+			name:   "Cloud Run preset with its resource type",
+			filter: Filter{ResourceType: "cloud_run_revision", PresetValues: map[string]string{"run-service": "api"}},
+			want:   `resource.type = "cloud_run_revision" AND resource.labels.service_name = "api"`,
+		},
+		{
+			name:   "Cloud Functions preset",
+			filter: Filter{PresetValues: map[string]string{"function": "ingest"}},
+			want: `((resource.type = "cloud_function" AND resource.labels.function_name = "ingest")` +
+				` OR (resource.type = "cloud_run_revision" AND resource.labels.service_name = "ingest"))`,
+		},
+		{
+			name:   "Compute Engine preset by name",
+			filter: Filter{PresetValues: map[string]string{"instance": "web-1"}},
+			want:   `resource.type = "gce_instance" AND labels."compute.googleapis.com/resource_name" = "web-1"`,
+		},
+		{
+			name:   "Compute Engine preset by ID",
+			filter: Filter{PresetValues: map[string]string{"instance": "1234567"}},
+			want:   `resource.type = "gce_instance" AND resource.labels.instance_id = "1234567"`,
+		},
+		{
+			name:   "Kubernetes shortcuts",
+			filter: Filter{Namespace: "payments", Pod: "api-", Container: "server"},
+			want:   `resource.type = "k8s_container" AND resource.labels.namespace_name = "payments" AND resource.labels.pod_name =~ "^api-" AND resource.labels.container_name = "server"`,
+		},
+		{
+			name:   "time window keeps fractional seconds",
+			filter: Filter{SinceTime: time.Date(2026, 10, 15, 9, 30, 0, 123_000_000, time.UTC), UntilTime: time.Date(2026, 10, 15, 10, 0, 0, 0, time.UTC)},
+			want:   `timestamp >= "2026-10-15T09:30:00.123Z" AND timestamp <= "2026-10-15T10:00:00Z"`,
+		},
+		{
+			name:   "phrase search",
+			filter: Filter{Search: "connection reset"},
+			want:   "SEARCH(\"`connection reset`\")",
+		},
+		{
+			name:   "search in fields",
+			filter: Filter{Search: `say "hi"`, SearchFields: []string{"textPayload", "jsonPayload.message"}},
+			want:   `(textPayload:"say \"hi\"" OR jsonPayload.message:"say \"hi\"")`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := BuildFilterString(&tt.filter)
+			if got != tt.want {
+				t.Errorf("BuildFilterString() =\n%s\nwant\n%s", got, tt.want)
+			}
+
+			if _, err := ParseQuery(got); err != nil {
+				t.Errorf("BuildFilterString() does not parse: %v", err)
+			}
+		})
+	}
+}
+
+func FuzzBuildFilterString(f *testing.F) {
+	f.Add(`projects/p/logs/a"b\c`, "k8s_container", "api-", "team", "pay\"ments", "connection reset", `severity=ERROR OR textPayload:"timeout"`)
+	f.Add("", "", "^api-[0-9]+$", "app.kubernetes.io/name", `\`, "`a b`", "severity>=ERROR -- only errors")
+	f.Add("", "", "", "", "", "", "-- only a comment")
+
+	f.Fuzz(func(t *testing.T, logName, resourceType, pod, labelKey, labelValue, search, custom string) {
+		// A custom filter that does not parse on its own is rejected before it is combined
+		if _, err := ParseQuery(custom); err != nil {
+			t.Skip()
+		}
+
+		filter := Filter{
+			LogName:      logName,
+			ResourceType: resourceType,
+			Pod:          pod,
+			Search:       search,
+			CustomFilter: custom,
+			Severities:   []string{"WARNING", "ERROR"},
+			SinceTime:    time.Date(2026, 10, 15, 9, 30, 0, 0, time.UTC),
+		}
+		if labelKey != "" {
+			filter.Selector = []LabelRequirement{{Key: labelKey, Operator: SelectorNotIn, Values: []string{labelValue, "x"}}}
+		}
+
+		for _, fields := range [][]string{nil, {"textPayload", "jsonPayload.message"}} {
+			filter.SearchFields = fields
+
+			got := BuildFilterString(&filter)
+			if _, err := ParseQuery(got); err != nil {
+				t.Fatalf("BuildFilterString(%+v) does not parse:\n%s\n%v", filter, got, err)
+			}
+		}
+	})
+}
+
+func TestFormatProtoPayload(t *testing.T) {
+	auditLog, err := anypb.New(&audit.AuditLog{
+		ServiceName:  "bigquery.googleapis.com",
+		MethodName:   "jobservice.insert",
+		ResourceName: "projects/p/jobs/job-1",
+		// Legacy BigQuery audit logs nest an AuditData message, whose type is not registered
+		ServiceData: &anypb.Any{TypeUrl: "type.googleapis.com/google.cloud.bigquery.logging.v1.AuditData", Value: []byte{0x0a, 0x01, 'x'}},
+	})
+	if err != nil {
+		t.Fatalf("anypb.New returned an error: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		payload *anypb.Any
+		want    string
+	}{
+		{
+			name:    "audit log with an unknown nested type",
+			payload: auditLog,
+			want:    "bigquery.googleapis.com jobservice.insert projects/p/jobs/job-1",
+		},
+		{
+			name:    "unknown type",
+			payload: &anypb.Any{TypeUrl: "type.googleapis.com/example.v1.Event", Value: []byte("raw")},
+			want:    `type.googleapis.com/example.v1.Event {"@type":"type.googleapis.com/example.v1.Event","value":"cmF3"}`,
+		},
+		{
+			name:    "known type that cannot be decoded",
+			payload: &anypb.Any{TypeUrl: "type.googleapis.com/google.cloud.audit.AuditLog", Value: []byte{0xff}},
+			want:    `type.googleapis.com/google.cloud.audit.AuditLog {"@type":"type.googleapis.com/google.cloud.audit.AuditLog","value":"/w=="}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := formatProtoPayload(protoPayloadFields(tt.payload))
+			if got != tt.want {
+				t.Errorf("formatProtoPayload() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,193 @@
+package stream
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// GrepOptions selects entries by matching their message with regular expressions, after they are fetched
+type GrepOptions struct {
+	// Pattern selects the entries whose message matches, empty to select all entries
+	Pattern string
+	// InvertPattern excludes the entries whose message matches, empty to exclude none
+	InvertPattern string
+	IgnoreCase    bool
+	// Before and After are the numbers of entries of the same resource printed around each selected entry
+	Before int
+	After  int
+}
+
+// Validate reports whether the patterns are valid regular expressions, before any output is written
+func (o *GrepOptions) Validate() error {
+	_, _, err := o.compile()
+	return err
+}
+
+// compile returns the regular expressions of the options, nil when a pattern is empty
+func (o *GrepOptions) compile() (match *regexp.Regexp, invert *regexp.Regexp, err error) {
+	flags := ""
+	if o.IgnoreCase {
+		flags = "(?i)"
+	}
+
+	if o.Pattern != "" {
+		match, err = regexp.Compile(flags + o.Pattern)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid grep pattern %q: \n%w", o.Pattern, err)
+		}
+	}
+
+	if o.InvertPattern != "" {
+		invert, err = regexp.Compile(flags + o.InvertPattern)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid grep pattern %q: \n%w", o.InvertPattern, err)
+		}
+	}
+
+	return match, invert, nil
+}
+
+// highlightPattern returns the pattern whose matches are highlighted, nil without grep options or an invalid pattern
+func highlightPattern(options *GrepOptions) *regexp.Regexp {
+	if options == nil {
+		return nil
+	}
+
+	match, _, err := options.compile()
+	if err != nil {
+		return nil
+	}
+
+	return match
+}
+
+// grepContext is the context of the entries of a resource: the entries seen before the next selected one,
+// and the number of entries still to print after the last selected one
+type grepContext struct {
+	before []*Entry
+	after  int
+	// printed is set once an entry of the resource was printed, and skipped when an entry was left out since then
+	printed bool
+	skipped bool
+}
+
+// grepPrinter prints the entries selected by the grep options, and the entries around them from the same resource
+type grepPrinter struct {
+	printer  Printer
+	match    *regexp.Regexp
+	invert   *regexp.Regexp
+	before   int
+	after    int
+	contexts map[string]*grepContext
+}
+
+func newGrepPrinter(printer Printer, options *GrepOptions) (*grepPrinter, error) {
+	match, invert, err := options.compile()
+	if err != nil {
+		return nil, err
+	}
+
+	return &grepPrinter{
+		printer:  printer,
+		match:    match,
+		invert:   invert,
+		before:   max(options.Before, 0),
+		after:    max(options.After, 0),
+		contexts: make(map[string]*grepContext),
+	}, nil
+}
+
+func (p *grepPrinter) Print(entry *Entry) error {
+	key := resourceKey(entry)
+	state := p.contexts[key]
+	if state == nil {
+		state = &grepContext{}
+		p.contexts[key] = state
+	}
+
+	if !p.selects(entry) {
+		if state.after > 0 {
+			state.after--
+			return p.print(state, entry)
+		}
+
+		state.before = append(state.before, entry)
+		if len(state.before) > p.before {
+			state.before = state.before[1:]
+			state.skipped = true
+		}
+
+		return nil
+	}
+
+	for _, previous := range state.before {
+		if err := p.print(state, previous); err != nil {
+			return err
+		}
+	}
+	state.before = nil
+	state.after = p.after
+
+	return p.print(state, entry)
+}
+
+// print prints an entry of a resource, after a "--" separator when it starts a new group of context like grep
+func (p *grepPrinter) print(state *grepContext, entry *Entry) error {
+	if state.printed && state.skipped && (p.before > 0 || p.after > 0) {
+		if err := printSeparator(p.printer); err != nil {
+			return err
+		}
+	}
+	state.printed = true
+	state.skipped = false
+
+	return p.printer.Print(entry)
+}
+
+func (p *grepPrinter) PrintSuppression(suppression Suppression) error {
+	return printSuppression(p.printer, suppression)
+}
+
+func (p *grepPrinter) Close() error {
+	return p.printer.Close()
+}
+
+func (p *grepPrinter) selects(entry *Entry) bool {
+	message := entry.Message()
+
+	if p.match != nil && !p.match.MatchString(message) {
+		return false
+	}
+
+	return p.invert == nil || !p.invert.MatchString(message)
+}
+
+// resourceKey identifies the resource of an entry, e.g. a pod, by its type and labels
+func resourceKey(entry *Entry) string {
+	labels := make([]string, 0, len(entry.Resource.Labels))
+	for key, value := range entry.Resource.Labels {
+		labels = append(labels, key+"="+value)
+	}
+	sort.Strings(labels)
+
+	return entry.Resource.Type + "{" + strings.Join(labels, ",") + "}"
+}
+
+// highlightMatches highlights the substrings of message matched by pattern when stdout is a terminal
+func highlightMatches(message string, pattern *regexp.Regexp) string {
+	if pattern == nil || !term.IsTerminal(int(os.Stdout.Fd())) {
+		return message
+	}
+
+	return pattern.ReplaceAllStringFunc(message, func(match string) string {
+		if match == "" {
+			return match
+		}
+		return "\033[1;31m" + match + "\033[0m" // Bold red
+	})
+}
@@ -0,0 +1,146 @@
+package stream
+
+import (
+	"fmt"
+	"testing"
+)
+
+// separatorRecorder is a printerFunc that also renders the separators of grep context groups
+type separatorRecorder struct {
+	printerFunc
+	separator func() error
+}
+
+func (r separatorRecorder) PrintSeparator() error {
+	return r.separator()
+}
+
+// grepEntry returns an entry of a pod of the api or worker deployments with a text message
+func grepEntry(insertID string, pod string, message string) *Entry {
+	return &Entry{
+		InsertID:    insertID,
+		Resource:    Resource{Type: "k8s_container", Labels: map[string]string{"pod_name": pod}},
+		TextPayload: message,
+	}
+}
+
+func TestGrepPrinter(t *testing.T) {
+	// The entries of the api and worker pods interleave
+	entries := []*Entry{
+		grepEntry("a1", "api", "starting"),
+		grepEntry("w1", "worker", "starting"),
+		grepEntry("a2", "api", "request ok"),
+		grepEntry("w2", "worker", "ERROR: queue full"),
+		grepEntry("a3", "api", "error: timeout"),
+		grepEntry("a4", "api", "request ok"),
+		grepEntry("w3", "worker", "retrying"),
+		grepEntry("a5", "api", "request ok"),
+		grepEntry("a6", "api", "request ok"),
+		grepEntry("a7", "api", "error: reset"),
+		grepEntry("a8", "api", "done"),
+	}
+
+	tests := []struct {
+		name    string
+		options GrepOptions
+		want    []string
+	}{
+		{
+			name:    "pattern",
+			options: GrepOptions{Pattern: "error"},
+			want:    []string{"a3", "a7"},
+		},
+		{
+			name:    "ignore case",
+			options: GrepOptions{Pattern: "error", IgnoreCase: true},
+			want:    []string{"w2", "a3", "a7"},
+		},
+		{
+			name:    "invert pattern",
+			options: GrepOptions{InvertPattern: "request ok"},
+			want:    []string{"a1", "w1", "w2", "a3", "w3", "a7", "a8"},
+		},
+		{
+			name:    "invert pattern ignoring case",
+			options: GrepOptions{InvertPattern: "ERROR|REQUEST", IgnoreCase: true},
+			want:    []string{"a1", "w1", "w3", "a8"},
+		},
+		{
+			name:    "pattern and invert pattern",
+			options: GrepOptions{Pattern: "error", InvertPattern: "reset"},
+			want:    []string{"a3"},
+		},
+		{
+			name:    "after context from the same resource",
+			options: GrepOptions{Pattern: "error", IgnoreCase: true, After: 1},
+			want:    []string{"w2", "a3", "a4", "w3", "--", "a7", "a8"},
+		},
+		{
+			name:    "before context from the same resource",
+			options: GrepOptions{Pattern: "error", IgnoreCase: true, Before: 2},
+			want:    []string{"w1", "w2", "a1", "a2", "a3", "--", "a5", "a6", "a7"},
+		},
+		{
+			name:    "before and after context",
+			options: GrepOptions{Pattern: "timeout|reset", Before: 1, After: 1},
+			want:    []string{"a2", "a3", "a4", "--", "a6", "a7", "a8"},
+		},
+		{
+			name:    "contiguous groups are not separated",
+			options: GrepOptions{Pattern: "request", Before: 1},
+			want:    []string{"a1", "a2", "a3", "a4", "a5", "a6"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			recorder := separatorRecorder{
+				printerFunc: func(entry *Entry) error {
+					got = append(got, entry.InsertID)
+					return nil
+				},
+				separator: func() error {
+					got = append(got, "--")
+					return nil
+				},
+			}
+
+			printer, err := newGrepPrinter(recorder, &tt.options)
+			if err != nil {
+				t.Fatalf("newGrepPrinter() returned an error: %v", err)
+			}
+
+			for _, entry := range entries {
+				if err := printer.Print(entry); err != nil {
+					t.Fatalf("Print() returned an error: %v", err)
+				}
+			}
+
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("printed %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGrepOptionsValidate(t *testing.T) {
+	tests := []struct {
+		options GrepOptions
+		wantErr bool
+	}{
+		{options: GrepOptions{}},
+		{options: GrepOptions{Pattern: `timeout after \d+s`, InvertPattern: "^GET /healthz"}},
+		{options: GrepOptions{Pattern: "("}, wantErr: true},
+		{options: GrepOptions{InvertPattern: "[a-"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.options.Pattern+"|"+tt.options.InvertPattern, func(t *testing.T) {
+			err := tt.options.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
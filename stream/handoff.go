@@ -0,0 +1,89 @@
+package stream
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+const (
+	// tailBufferSize is the number of stream responses buffered while the history is fetched
+	tailBufferSize = 1024
+	// handoffWindow is how long printed entries are remembered, relative to the newest one, to skip duplicates
+	handoffWindow = 5 * time.Minute
+	// pruneThreshold is the number of remembered entries above which the oldest ones are forgotten
+	pruneThreshold = 10000
+)
+
+// History describes the historical fetch printed before live entries when following logs
+type History struct {
+	Filter string
+	Limit  int
+	Order  string
+	// resume marks the backfill of a reconnected stream, which is quiet when it finds no entries
+	resume bool
+}
+
+// printerFunc adapts a function to the Printer interface
+type printerFunc func(entry *Entry) error
+
+func (f printerFunc) Print(entry *Entry) error {
+	return f(entry)
+}
+
+func (f printerFunc) Close() error {
+	return nil
+}
+
+// entryKey identifies an entry, the insertId is only unique for a given timestamp
+func entryKey(entry *Entry) string {
+	return fmt.Sprintf("%s|%d", entry.InsertID, entry.Timestamp.UnixNano())
+}
+
+// entrySet records the entries already printed with their timestamp
+type entrySet struct {
+	keys map[string]time.Time
+}
+
+func newEntrySet() *entrySet {
+	return &entrySet{keys: make(map[string]time.Time)}
+}
+
+func (s *entrySet) add(entry *Entry) {
+	if entry.InsertID != "" {
+		s.keys[entryKey(entry)] = entry.Timestamp
+	}
+}
+
+func (s *entrySet) contains(entry *Entry) bool {
+	_, ok := s.keys[entryKey(entry)]
+	return ok && entry.InsertID != ""
+}
+
+// prune forgets the entries older than before once the set grows above pruneThreshold
+func (s *entrySet) prune(before time.Time) {
+	if len(s.keys) < pruneThreshold {
+		return
+	}
+
+	for key, timestamp := range s.keys {
+		if timestamp.Before(before) {
+			delete(s.keys, key)
+		}
+	}
+}
+
+// sortEntries sorts entries by timestamp, then insertId
+func sortEntries(entries []*Entry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entryLess(entries[i], entries[j])
+	})
+}
+
+// entryLess reports whether a sorts before b, by timestamp then insertId
+func entryLess(a, b *Entry) bool {
+	if !a.Timestamp.Equal(b.Timestamp) {
+		return a.Timestamp.Before(b.Timestamp)
+	}
+	return a.InsertID < b.InsertID
+}
@@ -0,0 +1,120 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	loggingv2 "cloud.google.com/go/logging/apiv2"
+	"cloud.google.com/go/logging/apiv2/loggingpb"
+	"google.golang.org/api/iterator"
+)
+
+// scopeIterator iterates over the historical entries of a scope, keeping the next entry to merge
+type scopeIterator struct {
+	scope   Scope
+	entries *loggingv2.LogEntryIterator
+	head    *Entry
+	done    bool
+}
+
+// next loads the next entry of the scope into head, unless it is already loaded or the scope has no more entries
+func (p *scopeIterator) next() error {
+	if p.head != nil || p.done {
+		return nil
+	}
+
+	entry, err := p.entries.Next()
+	if errors.Is(err, iterator.Done) {
+		p.done = true
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to fetch entries of %s: \n%w", p.scope, err)
+	}
+
+	p.head = entryFromProto(entry)
+	return nil
+}
+
+// mergedIterator merges the historical entries of several scopes in timestamp order
+type mergedIterator struct {
+	client      *loggingv2.Client
+	scopes      []*scopeIterator
+	newestFirst bool
+}
+
+// newMergedIterator lists the entries of each scope. Entries are read as protos, like the stream,
+// so that a protoPayload of an unknown type is shown with its type URL instead of failing the whole listing.
+func newMergedIterator(ctx context.Context, scopes []Scope, filter string, newestFirst bool) (*mergedIterator, error) {
+	client, err := loggingv2.NewClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create logging client: \n%w", err)
+	}
+
+	orderBy := "timestamp asc"
+	if newestFirst {
+		orderBy = "timestamp desc"
+	}
+
+	m := &mergedIterator{client: client, newestFirst: newestFirst}
+	for _, scope := range scopes {
+		m.scopes = append(m.scopes, &scopeIterator{
+			scope: scope,
+			entries: client.ListLogEntries(ctx, &loggingpb.ListLogEntriesRequest{
+				ResourceNames: []string{scope.Name},
+				Filter:        historyFilter(filter, time.Now()),
+				OrderBy:       orderBy,
+			}),
+		})
+	}
+
+	return m, nil
+}
+
+// historyFilter looks back 24 hours from now when the filter has no timestamp clause, as the logadmin client does
+func historyFilter(filter string, now time.Time) string {
+	if strings.Contains(strings.ToLower(filter), "timestamp") {
+		return filter
+	}
+
+	lookback := comparison{"timestamp", opGreaterEq, now.Add(-24 * time.Hour).UTC().Format(time.RFC3339)}
+	if filter == "" {
+		return lookback.String()
+	}
+
+	return and{userExpr(filter), lookback}.String()
+}
+
+// Next returns the oldest (or newest) next entry across the scopes, or iterator.Done when all scopes are exhausted
+func (m *mergedIterator) Next() (*Entry, error) {
+	var next *scopeIterator
+	for _, scope := range m.scopes {
+		if err := scope.next(); err != nil {
+			return nil, err
+		}
+
+		if scope.head == nil {
+			continue
+		}
+
+		if next == nil || entryLess(scope.head, next.head) != m.newestFirst {
+			next = scope
+		}
+	}
+
+	if next == nil {
+		return nil, iterator.Done
+	}
+
+	entry := next.head
+	next.head = nil
+
+	return entry, nil
+}
+
+func (m *mergedIterator) Close() {
+	m.client.Close()
+}
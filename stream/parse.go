@@ -0,0 +1,600 @@
+package stream
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Query is a parsed logging query
+type Query struct {
+	text string
+	root expr // nil for an empty query, which matches every entry
+	// compiled caches the regular expressions compiled to evaluate the query
+	compiled map[string]*regexp.Regexp
+}
+
+// ParseQuery parses a query of the logging query language: comparisons (=, !=, <, <=, >, >=, :, =~, !~),
+// AND, OR and NOT (or a leading -), parentheses, function calls such as SEARCH("text"), quoted strings,
+// bare values searched in all fields, and -- comments. Errors are reported as a *ParseError.
+func ParseQuery(text string) (*Query, error) {
+	tokens, err := lex(text)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{text: text, tokens: tokens}
+	if p.peek().kind == tokEOF {
+		return &Query{text: text}, nil
+	}
+
+	root, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	if tok := p.peek(); tok.kind != tokEOF {
+		if tok.kind == tokRParen {
+			return nil, p.errorAt(tok, "unbalanced \")\"", "remove it or add the matching \"(\"")
+		}
+		return nil, p.errorAt(tok, fmt.Sprintf("unexpected %s", tok), "")
+	}
+
+	return &Query{text: text, root: root}, nil
+}
+
+// String returns the query as written
+func (q *Query) String() string {
+	return q.text
+}
+
+// isEmptyQuery reports whether a query has no expression, e.g. when it is only made of comments
+func isEmptyQuery(text string) bool {
+	query, err := ParseQuery(text)
+	return err == nil && query.root == nil
+}
+
+// ParseError reports a syntax error in a query, with the position of the offending token
+type ParseError struct {
+	Query   string
+	Offset  int // byte offset of the offending token
+	Message string
+	Hint    string // suggested fix, if any
+}
+
+// Error renders the error with the offending line and a caret under the offending column
+func (e *ParseError) Error() string {
+	lineStart := strings.LastIndex(e.Query[:e.Offset], "\n") + 1
+	lineEnd := len(e.Query)
+	if i := strings.Index(e.Query[e.Offset:], "\n"); i >= 0 {
+		lineEnd = e.Offset + i
+	}
+
+	line := strings.Count(e.Query[:e.Offset], "\n") + 1
+	column := utf8.RuneCountInString(e.Query[lineStart:e.Offset]) + 1
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s at line %d, column %d\n", e.Message, line, column)
+	fmt.Fprintf(&b, "  %s\n", e.Query[lineStart:lineEnd])
+	fmt.Fprintf(&b, "  %s^", strings.Repeat(" ", column-1))
+	if e.Hint != "" {
+		fmt.Fprintf(&b, "\n  hint: %s", e.Hint)
+	}
+
+	return b.String()
+}
+
+// Kinds of tokens
+const (
+	tokEOF = iota
+	tokWord
+	tokString
+	tokOp
+	tokLParen
+	tokRParen
+	tokComma
+	tokMinus
+)
+
+type token struct {
+	kind   int
+	text   string // the word, the unquoted string or the operator
+	offset int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of query"
+	case tokString:
+		return quoteString(t.text)
+	default:
+		return fmt.Sprintf("%q", t.text)
+	}
+}
+
+// operators lists the comparison operators, longest first
+var operators = []string{opRegex, opNotRegex, opNotEquals, opLessEq, opGreaterEq, opEquals, opLess, opGreater, opHas}
+
+// isWordRune reports whether r can be part of a bare word, such as a field path or an unquoted value
+func isWordRune(r rune) bool {
+	return !unicode.IsSpace(r) && !strings.ContainsRune(`()"'=!<>:~,&|`, r)
+}
+
+// lex splits a query into tokens
+func lex(text string) ([]token, error) {
+	var tokens []token
+
+	syntaxError := func(offset int, message, hint string) error {
+		return &ParseError{Query: text, Offset: offset, Message: message, Hint: hint}
+	}
+
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+
+		switch {
+		case unicode.IsSpace(r):
+			i += size
+
+		case r == '(':
+			tokens = append(tokens, token{kind: tokLParen, text: "(", offset: i})
+			i++
+
+		case r == ')':
+			tokens = append(tokens, token{kind: tokRParen, text: ")", offset: i})
+			i++
+
+		case r == ',':
+			tokens = append(tokens, token{kind: tokComma, text: ",", offset: i})
+			i++
+
+		case r == '"':
+			value, end, err := lexString(text, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{kind: tokString, text: value, offset: i})
+			i = end
+
+		case r == '\'':
+			return nil, syntaxError(i, "unexpected single quote", "quote strings with double quotes, e.g. \"value\"")
+
+		case strings.HasPrefix(text[i:], "=="):
+			return nil, syntaxError(i, `unknown operator "=="`, `use "=" to test equality`)
+
+		case strings.HasPrefix(text[i:], "&&"):
+			return nil, syntaxError(i, `unknown operator "&&"`, "use AND to combine expressions")
+
+		case strings.HasPrefix(text[i:], "||"):
+			return nil, syntaxError(i, `unknown operator "||"`, "use OR to combine expressions")
+
+		case strings.HasPrefix(text[i:], "--"):
+			// A comment runs to the end of the line
+			if end := strings.IndexByte(text[i:], '\n'); end >= 0 {
+				i += end
+			} else {
+				i = len(text)
+			}
+
+		case r == '-' && !afterOperator(tokens) && i+1 < len(text) && !unicode.IsSpace(rune(text[i+1])):
+			// A leading minus negates the next expression, e.g. -resource.type="gce_instance"
+			tokens = append(tokens, token{kind: tokMinus, text: "-", offset: i})
+			i++
+
+		case isWordRune(r):
+			start := i
+			var word strings.Builder
+			for i < len(text) {
+				r, size := utf8.DecodeRuneInString(text[i:])
+
+				// Quoted segments of field paths are part of the word, e.g. labels."k8s-pod/app"
+				if r == '"' && strings.HasSuffix(word.String(), ".") {
+					_, end, err := lexString(text, i)
+					if err != nil {
+						return nil, err
+					}
+					word.WriteString(text[i:end])
+					i = end
+					continue
+				}
+
+				if !isWordRune(r) {
+					break
+				}
+				word.WriteRune(r)
+				i += size
+			}
+			tokens = append(tokens, token{kind: tokWord, text: word.String(), offset: start})
+
+		default:
+			op := ""
+			for _, candidate := range operators {
+				if strings.HasPrefix(text[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+
+			if op == "" {
+				return nil, syntaxError(i, fmt.Sprintf("unexpected character %q", r), "")
+			}
+
+			tokens = append(tokens, token{kind: tokOp, text: op, offset: i})
+			i += len(op)
+		}
+	}
+
+	return append(tokens, token{kind: tokEOF, offset: len(text)}), nil
+}
+
+// lexString reads the double-quoted string starting at start, and returns its unescaped value and end offset
+func lexString(text string, start int) (string, int, error) {
+	var value strings.Builder
+
+	for i := start + 1; i < len(text); i++ {
+		switch text[i] {
+		case '\\':
+			if i+1 >= len(text) {
+				break
+			}
+			i++
+			switch text[i] {
+			case 'n':
+				value.WriteByte('\n')
+			case 't':
+				value.WriteByte('\t')
+			case '"', '\\':
+				value.WriteByte(text[i])
+			default:
+				// Other escapes are kept verbatim, e.g. the \d of a regular expression
+				value.WriteByte('\\')
+				value.WriteByte(text[i])
+			}
+		case '"':
+			return value.String(), i + 1, nil
+		default:
+			value.WriteByte(text[i])
+		}
+	}
+
+	return "", 0, &ParseError{Query: text, Offset: start, Message: "unterminated string", Hint: "add the closing double quote"}
+}
+
+// afterOperator reports whether the last token is a comparison operator, after which a minus starts a negative value
+func afterOperator(tokens []token) bool {
+	return len(tokens) > 0 && tokens[len(tokens)-1].kind == tokOp
+}
+
+// parser is a recursive descent parser of the logging query language.
+// OR binds more tightly than AND, and adjacent expressions are combined with AND.
+type parser struct {
+	text   string
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *parser) errorAt(tok token, message, hint string) error {
+	return &ParseError{Query: p.text, Offset: tok.offset, Message: message, Hint: hint}
+}
+
+// isKeyword reports whether tok is the keyword, and returns an error for its lowercase form
+func (p *parser) isKeyword(tok token, keyword string) (bool, error) {
+	if tok.kind != tokWord {
+		return false, nil
+	}
+
+	if tok.text == keyword {
+		return true, nil
+	}
+
+	if strings.EqualFold(tok.text, keyword) {
+		return false, p.errorAt(tok, fmt.Sprintf("%q is searched as a value, not used as an operator", tok.text),
+			fmt.Sprintf("write %s in uppercase, or quote it to search for the word", keyword))
+	}
+
+	return false, nil
+}
+
+// parseAnd parses expressions combined with AND, explicitly or by juxtaposition
+func (p *parser) parseAnd() (expr, error) {
+	var terms and
+
+	for {
+		term, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, term)
+
+		tok := p.peek()
+		if tok.kind == tokEOF || tok.kind == tokRParen {
+			break
+		}
+
+		isAnd, err := p.isKeyword(tok, "AND")
+		if err != nil {
+			return nil, err
+		}
+		if isAnd {
+			p.next()
+		}
+	}
+
+	if len(terms) == 1 {
+		return terms[0], nil
+	}
+
+	return terms, nil
+}
+
+// parseOr parses expressions combined with OR
+func (p *parser) parseOr() (expr, error) {
+	var terms or
+
+	for {
+		term, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, term)
+
+		isOr, err := p.isKeyword(p.peek(), "OR")
+		if err != nil {
+			return nil, err
+		}
+		if !isOr {
+			break
+		}
+		p.next()
+	}
+
+	if len(terms) == 1 {
+		return terms[0], nil
+	}
+
+	return terms, nil
+}
+
+// parseUnary parses a negated expression or a primary expression
+func (p *parser) parseUnary() (expr, error) {
+	tok := p.peek()
+
+	isNot, err := p.isKeyword(tok, "NOT")
+	if err != nil {
+		return nil, err
+	}
+
+	if isNot || tok.kind == tokMinus {
+		p.next()
+		negated, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return not{negated}, nil
+	}
+
+	return p.parsePrimary()
+}
+
+// parsePrimary parses a parenthesized expression, a comparison, a function call or a bare value
+func (p *parser) parsePrimary() (expr, error) {
+	tok := p.next()
+
+	switch tok.kind {
+	case tokLParen:
+		if p.peek().kind == tokRParen {
+			return nil, p.errorAt(p.peek(), "empty parentheses", "")
+		}
+
+		inner, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+
+		if closing := p.next(); closing.kind != tokRParen {
+			return nil, p.errorAt(tok, "unbalanced \"(\"", "add the matching \")\"")
+		}
+		return group{inner}, nil
+
+	case tokString:
+		return globalTerm{tok.text}, nil
+
+	case tokWord:
+		for _, keyword := range []string{"AND", "OR"} {
+			if tok.text == keyword {
+				return nil, p.errorAt(tok, fmt.Sprintf("%s is missing an expression on its left", keyword), "")
+			}
+		}
+
+		switch next := p.peek(); {
+		case next.kind == tokOp:
+			return p.parseComparison(tok)
+		case next.kind == tokLParen && isFunctionName(tok.text):
+			return p.parseCall(tok)
+		}
+
+		return globalTerm{tok.text}, nil
+
+	case tokEOF:
+		return nil, p.errorAt(tok, "unexpected end of query", "an expression is missing")
+
+	default:
+		return nil, p.errorAt(tok, fmt.Sprintf("unexpected %s", tok), "")
+	}
+}
+
+// parseComparison parses the operator and value of a comparison on the field path of fieldTok
+func (p *parser) parseComparison(fieldTok token) (expr, error) {
+	op := p.next()
+	return p.parseValue(fieldTok.text, op)
+}
+
+// parseValue parses the value of a comparison, or a parenthesized combination of values, e.g. severity=(ERROR OR CRITICAL)
+func (p *parser) parseValue(path string, op token) (expr, error) {
+	tok := p.next()
+
+	switch tok.kind {
+	case tokString:
+		return newComparison(path, op.text, tok.text), nil
+
+	case tokWord:
+		if tok.text == "*" && op.text == opHas {
+			return exists{path}, nil
+		}
+		return newComparison(path, op.text, tok.text), nil
+
+	case tokMinus:
+		// A negative number, e.g. jsonPayload.offset > -1
+		value := p.next()
+		if value.kind != tokWord {
+			return nil, p.errorAt(value, fmt.Sprintf("expected a number after %q", "-"), "")
+		}
+		return newComparison(path, op.text, "-"+value.text), nil
+
+	case tokLParen:
+		var values []expr
+		joiner := ""
+		for {
+			value, err := p.parseValue(path, op)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+
+			next := p.next()
+			if next.kind == tokRParen {
+				break
+			}
+
+			isOr, err := p.isKeyword(next, "OR")
+			if err != nil {
+				return nil, err
+			}
+			isAnd, err := p.isKeyword(next, "AND")
+			if err != nil {
+				return nil, err
+			}
+
+			keyword := "OR"
+			switch {
+			case isAnd:
+				keyword = "AND"
+			case !isOr:
+				return nil, p.errorAt(next, fmt.Sprintf("unexpected %s in the values of %s", next, path), "combine values with OR or AND, e.g. (a OR b)")
+			}
+
+			if joiner != "" && joiner != keyword {
+				return nil, p.errorAt(next, "OR and AND are mixed in the values of "+path, "group the values with parentheses, e.g. (a OR (b AND c))")
+			}
+			joiner = keyword
+		}
+
+		if joiner == "AND" {
+			return group{and(values)}, nil
+		}
+		return or(values), nil
+
+	default:
+		return nil, p.errorAt(tok, fmt.Sprintf("missing value after %s %s", path, op.text), "")
+	}
+}
+
+// parseCall parses the arguments of a function call, e.g. SEARCH("text") or sample(insertId, 0.25)
+func (p *parser) parseCall(name token) (expr, error) {
+	open := p.next()
+
+	var args []token
+	if p.peek().kind == tokRParen {
+		p.next()
+		return funcCall{name: name.text}, nil
+	}
+
+	for {
+		arg := p.next()
+		if arg.kind != tokWord && arg.kind != tokString {
+			return nil, p.errorAt(arg, fmt.Sprintf("expected an argument of %s, got %s", name.text, arg), "")
+		}
+		args = append(args, arg)
+
+		switch sep := p.next(); sep.kind {
+		case tokComma:
+			continue
+		case tokRParen:
+			return newFuncCall(name.text, args), nil
+		case tokEOF:
+			return nil, p.errorAt(open, fmt.Sprintf("unbalanced \"(\" in the call of %s", name.text), "add the matching \")\"")
+		default:
+			return nil, p.errorAt(sep, fmt.Sprintf("unexpected %s in the arguments of %s", sep, name.text), "separate arguments with commas")
+		}
+	}
+}
+
+// isFunctionName reports whether a word followed by "(" is a function call rather than a value, e.g. SEARCH or log_id
+func isFunctionName(word string) bool {
+	return identifierPattern.MatchString(word)
+}
+
+// newComparison returns the node of a parsed comparison
+func newComparison(path, op, value string) expr {
+	if op == opHas {
+		return has{path, value}
+	}
+
+	return comparison{path, op, value}
+}
+
+// globalTerm is a bare value, matched against all the fields of an entry
+type globalTerm struct {
+	value string
+}
+
+func (g globalTerm) String() string {
+	return quoteString(g.value)
+}
+
+// funcCall is a parsed function call, whose arguments are field paths, numbers or strings
+type funcCall struct {
+	name string
+	args []funcArg
+}
+
+type funcArg struct {
+	value  string
+	quoted bool
+}
+
+func newFuncCall(name string, tokens []token) funcCall {
+	args := make([]funcArg, 0, len(tokens))
+	for _, tok := range tokens {
+		args = append(args, funcArg{value: tok.text, quoted: tok.kind == tokString})
+	}
+
+	return funcCall{name: name, args: args}
+}
+
+func (c funcCall) String() string {
+	args := make([]string, 0, len(c.args))
+	for _, arg := range c.args {
+		if arg.quoted {
+			args = append(args, quoteString(arg.value))
+			continue
+		}
+		args = append(args, arg.value)
+	}
+
+	return c.name + "(" + strings.Join(args, ", ") + ")"
+}
@@ -0,0 +1,140 @@
+package stream
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  expr
+	}{
+		{
+			name:  "empty",
+			query: "  ",
+			want:  nil,
+		},
+		{
+			name:  "comparison",
+			query: `severity>="ERROR"`,
+			want:  comparison{"severity", opGreaterEq, "ERROR"},
+		},
+		{
+			name:  "trailing comment",
+			query: `severity="ERROR" -- only errors`,
+			want:  comparison{"severity", opEquals, "ERROR"},
+		},
+		{
+			name:  "comment line",
+			query: "-- errors of the api\nseverity=\"ERROR\"\nresource.type=\"k8s_container\" -- on GKE",
+			want:  and{comparison{"severity", opEquals, "ERROR"}, comparison{"resource.type", opEquals, "k8s_container"}},
+		},
+		{
+			name:  "comment in a string",
+			query: `textPayload:"a -- b"`,
+			want:  has{"textPayload", "a -- b"},
+		},
+		{
+			name:  "regular expression escapes",
+			query: `textPayload=~"\d+\.\d+"`,
+			want:  comparison{"textPayload", opRegex, `\d+\.\d+`},
+		},
+		{
+			name:  "string escapes",
+			query: `jsonPayload.message="say \"hi\" \\ bye\n"`,
+			want:  comparison{"jsonPayload.message", opEquals, "say \"hi\" \\ bye\n"},
+		},
+		{
+			name:  "quoted field path",
+			query: `labels."k8s-pod/app"="api"`,
+			want:  comparison{`labels."k8s-pod/app"`, opEquals, "api"},
+		},
+		{
+			name:  "OR binds tighter than AND",
+			query: `a="1" b="2" OR c="3"`,
+			want:  and{comparison{"a", opEquals, "1"}, or{comparison{"b", opEquals, "2"}, comparison{"c", opEquals, "3"}}},
+		},
+		{
+			name:  "NOT and minus",
+			query: `NOT severity="DEBUG" AND -resource.type="gce_instance"`,
+			want:  and{not{comparison{"severity", opEquals, "DEBUG"}}, not{comparison{"resource.type", opEquals, "gce_instance"}}},
+		},
+		{
+			name:  "negative number",
+			query: `jsonPayload.offset > -1`,
+			want:  comparison{"jsonPayload.offset", opGreater, "-1"},
+		},
+		{
+			name:  "value list",
+			query: `severity=(ERROR OR CRITICAL)`,
+			want:  or{comparison{"severity", opEquals, "ERROR"}, comparison{"severity", opEquals, "CRITICAL"}},
+		},
+		{
+			name:  "exists",
+			query: `labels.env:*`,
+			want:  exists{"labels.env"},
+		},
+		{
+			name:  "function call",
+			query: "SEARCH(textPayload, \"`connection reset`\")",
+			want:  funcCall{name: "SEARCH", args: []funcArg{{value: "textPayload"}, {value: "`connection reset`", quoted: true}}},
+		},
+		{
+			name:  "global terms",
+			query: `timeout "connection reset"`,
+			want:  and{globalTerm{"timeout"}, globalTerm{"connection reset"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := ParseQuery(tt.query)
+			if err != nil {
+				t.Fatalf("ParseQuery(%q) returned an error: %v", tt.query, err)
+			}
+			if !reflect.DeepEqual(query.root, tt.want) {
+				t.Errorf("ParseQuery(%q) = %#v, want %#v", tt.query, query.root, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseQueryErrors(t *testing.T) {
+	tests := []struct {
+		query  string
+		offset int
+		hint   bool
+	}{
+		{query: `severity=="ERROR"`, offset: 8, hint: true},
+		{query: `a="1" && b="2"`, offset: 6, hint: true},
+		{query: `a="1" || b="2"`, offset: 6, hint: true},
+		{query: `severity='ERROR'`, offset: 9, hint: true},
+		{query: `a="1" and b="2"`, offset: 6, hint: true},
+		{query: `(a="1"`, offset: 0, hint: true},
+		{query: `a="1")`, offset: 5, hint: true},
+		{query: `a="1`, offset: 2, hint: true},
+		{query: `severity=`, offset: 9},
+		{query: `AND a="1"`, offset: 0},
+		{query: `a="1" -- b="2"` + "\n" + `OR`, offset: 17},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			_, err := ParseQuery(tt.query)
+
+			var parseErr *ParseError
+			if !errors.As(err, &parseErr) {
+				t.Fatalf("ParseQuery(%q) error = %v, want a *ParseError", tt.query, err)
+			}
+			if parseErr.Offset != tt.offset {
+				t.Errorf("ParseQuery(%q) error offset = %d, want %d\n%v", tt.query, parseErr.Offset, tt.offset, err)
+			}
+			if tt.hint && parseErr.Hint == "" {
+				t.Errorf("ParseQuery(%q) error has no hint\n%v", tt.query, err)
+			}
+		})
+	}
+}
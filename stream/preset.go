@@ -0,0 +1,157 @@
+package stream
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// PresetFlag is a command-line flag of a preset and the resource label it filters on
+type PresetFlag struct {
+	Name  string
+	Label string
+	Usage string
+}
+
+// Preset maps friendly flags to the resource types and label clauses used by a Google Cloud product
+type Preset struct {
+	Name          string
+	ResourceTypes []string
+	Flags         []PresetFlag
+	// DisplayLabels lists, for each resource type, the labels shown in the line prefix
+	DisplayLabels map[string][]string
+	// clauses builds the filter clauses from the flag values, it defaults to defaultPresetClauses
+	clauses func(preset *Preset, values map[string]string) []expr
+}
+
+// Presets is the registry of resource presets, in the order their flags are documented
+var Presets = []*Preset{
+	{
+		Name:          "Cloud Run",
+		ResourceTypes: []string{"cloud_run_revision"},
+		Flags: []PresetFlag{
+			{Name: "run-service", Label: "service_name", Usage: "Filter Cloud Run logs by service name"},
+			{Name: "revision", Label: "revision_name", Usage: "Filter Cloud Run logs by revision name (e.g. api-00042)"},
+		},
+		DisplayLabels: map[string][]string{"cloud_run_revision": {"service_name", "revision_name"}},
+	},
+	{
+		Name:          "Cloud Functions",
+		ResourceTypes: []string{"cloud_function", "cloud_run_revision"},
+		Flags: []PresetFlag{
+			{Name: "function", Label: "function_name", Usage: "Filter Cloud Functions logs (gen1 and gen2) by function name"},
+		},
+		DisplayLabels: map[string][]string{"cloud_function": {"function_name", "region"}},
+		clauses:       functionClauses,
+	},
+	{
+		Name:          "App Engine",
+		ResourceTypes: []string{"gae_app"},
+		Flags: []PresetFlag{
+			{Name: "gae-service", Label: "module_id", Usage: "Filter App Engine logs by service"},
+			{Name: "gae-version", Label: "version_id", Usage: "Filter App Engine logs by version (e.g. v3)"},
+		},
+		DisplayLabels: map[string][]string{"gae_app": {"module_id", "version_id"}},
+	},
+	{
+		Name:          "Compute Engine",
+		ResourceTypes: []string{"gce_instance"},
+		Flags: []PresetFlag{
+			{Name: "instance", Label: "instance_id", Usage: "Filter Compute Engine logs by instance name or numeric instance ID"},
+		},
+		DisplayLabels: map[string][]string{"gce_instance": {"instance_id", "zone"}},
+		clauses:       instanceClauses,
+	},
+}
+
+func init() {
+	// Presets choose the display labels of their resource types
+	for _, preset := range Presets {
+		for resourceType, labels := range preset.DisplayLabels {
+			resourceDisplayLabels[resourceType] = labels
+		}
+	}
+}
+
+// ActivePreset returns the preset whose flags are set in values, or an error when flags of several presets are combined
+func ActivePreset(values map[string]string) (*Preset, error) {
+	var active *Preset
+
+	for _, preset := range Presets {
+		if !preset.isSet(values) {
+			continue
+		}
+
+		if active != nil {
+			return nil, fmt.Errorf("%s and %s flags cannot be combined", active.Name, preset.Name)
+		}
+		active = preset
+	}
+
+	return active, nil
+}
+
+// exprs returns the filter clauses selecting the entries matching the preset flag values
+func (p *Preset) exprs(values map[string]string) []expr {
+	if p.clauses != nil {
+		return p.clauses(p, values)
+	}
+
+	return defaultPresetClauses(p, values)
+}
+
+// HasResourceType reports whether the preset targets the resource type
+func (p *Preset) HasResourceType(resourceType string) bool {
+	for _, t := range p.ResourceTypes {
+		if t == resourceType {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p *Preset) isSet(values map[string]string) bool {
+	for _, flag := range p.Flags {
+		if values[flag.Name] != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// defaultPresetClauses selects the first resource type of the preset and an equality clause per flag value
+func defaultPresetClauses(preset *Preset, values map[string]string) []expr {
+	clauses := []expr{comparison{"resource.type", opEquals, preset.ResourceTypes[0]}}
+
+	for _, flag := range preset.Flags {
+		if value := values[flag.Name]; value != "" {
+			clauses = append(clauses, comparison{field("resource", "labels", flag.Label), opEquals, value})
+		}
+	}
+
+	return clauses
+}
+
+// functionClauses matches gen1 functions (cloud_function) and gen2 functions, which run as Cloud Run services
+func functionClauses(_ *Preset, values map[string]string) []expr {
+	name := values["function"]
+
+	return []expr{or{
+		group{and{comparison{"resource.type", opEquals, "cloud_function"}, comparison{"resource.labels.function_name", opEquals, name}}},
+		group{and{comparison{"resource.type", opEquals, "cloud_run_revision"}, comparison{"resource.labels.service_name", opEquals, strings.ToLower(name)}}},
+	}}
+}
+
+// instanceClauses matches an instance by its numeric ID, or by its name through the label added by Compute Engine
+func instanceClauses(_ *Preset, values map[string]string) []expr {
+	instance := values["instance"]
+
+	clause := comparison{field("labels", "compute.googleapis.com/resource_name"), opEquals, instance}
+	if _, err := strconv.ParseUint(instance, 10, 64); err == nil {
+		clause = comparison{"resource.labels.instance_id", opEquals, instance}
+	}
+
+	return []expr{comparison{"resource.type", opEquals, "gce_instance"}, clause}
+}
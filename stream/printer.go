@@ -0,0 +1,513 @@
+package stream
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Supported output formats
+const (
+	FormatText   = "text"
+	FormatWide   = "wide"
+	FormatJSON   = "json"
+	FormatNDJSON = "ndjson"
+	FormatLogfmt = "logfmt"
+	FormatCSV    = "csv"
+)
+
+// Formats lists the supported output formats
+var Formats = []string{FormatText, FormatWide, FormatJSON, FormatNDJSON, FormatLogfmt, FormatCSV}
+
+// Printer renders entries to an output. Close must be called once all entries have been printed.
+type Printer interface {
+	Print(entry *Entry) error
+	Close() error
+}
+
+// Suppression reports live entries dropped by the stream, because of rate limits (RATE_LIMIT)
+// or because they were not read fast enough (NOT_CONSUMED)
+type Suppression struct {
+	Reason string
+	Count  int
+}
+
+// eventPrinter is implemented by printers that render stream events among entries
+type eventPrinter interface {
+	PrintSuppression(suppression Suppression) error
+}
+
+// printSuppression renders a suppression among entries when the printer supports events, and on stderr otherwise
+func printSuppression(printer Printer, suppression Suppression) error {
+	if printer, ok := printer.(eventPrinter); ok {
+		return printer.PrintSuppression(suppression)
+	}
+
+	_, err := fmt.Fprintf(os.Stderr, "[cloudtail] %d entries suppressed (%s)\n", suppression.Count, suppression.Reason)
+	return err
+}
+
+// separatorPrinter is implemented by printers that render lines of text, to separate the groups of grep context
+type separatorPrinter interface {
+	PrintSeparator() error
+}
+
+// printSeparator renders a "--" line between two groups of grep context, when the printer supports it
+func printSeparator(printer Printer) error {
+	if printer, ok := printer.(separatorPrinter); ok {
+		return printer.PrintSeparator()
+	}
+
+	return nil
+}
+
+// PrintOptions configures how entries are rendered
+type PrintOptions struct {
+	Format string
+	// ResourceLabels shows the display labels of the resource in the text line prefix
+	ResourceLabels bool
+	// ProjectPrefix starts text lines with the project of the entry, to tell apart the entries of several projects
+	ProjectPrefix bool
+	// Template is a Go text/template rendered for each entry, it takes precedence over Format
+	Template string
+	// Grep prints only the entries whose message matches, and their context, nil to print all entries
+	Grep *GrepOptions
+	// Append continues an output that already has content, the csv header is not written again
+	Append bool
+}
+
+// NewPrinter returns a Printer writing entries to out in the requested format
+func NewPrinter(out io.Writer, options PrintOptions) (Printer, error) {
+	printer, err := newFormatPrinter(out, options)
+	if err != nil || options.Grep == nil {
+		return printer, err
+	}
+
+	return newGrepPrinter(printer, options.Grep)
+}
+
+func newFormatPrinter(out io.Writer, options PrintOptions) (Printer, error) {
+	if options.Template != "" {
+		return newTemplatePrinter(out, options.Template)
+	}
+
+	switch options.Format {
+	case "", FormatText:
+		return &textPrinter{out: out, resourceLabels: options.ResourceLabels, projectPrefix: options.ProjectPrefix, highlight: highlightPattern(options.Grep)}, nil
+	case FormatWide:
+		return &textPrinter{out: out, wide: true, resourceLabels: true, projectPrefix: options.ProjectPrefix, highlight: highlightPattern(options.Grep)}, nil
+	case FormatJSON:
+		return &jsonPrinter{out: out}, nil
+	case FormatNDJSON:
+		return &ndjsonPrinter{out: out}, nil
+	case FormatLogfmt:
+		return &logfmtPrinter{out: out}, nil
+	case FormatCSV:
+		return &csvPrinter{writer: csv.NewWriter(out), headerWritten: options.Append}, nil
+	default:
+		return nil, fmt.Errorf("unknown output format: %q (valid values: %s)", options.Format, strings.Join(Formats, ", "))
+	}
+}
+
+// record is the stable schema used by the json, ndjson, logfmt and csv formats.
+//
+//	timestamp       RFC3339 timestamp with nanoseconds
+//	severity        DEFAULT, DEBUG, INFO, NOTICE, WARNING, ERROR, CRITICAL, ALERT or EMERGENCY
+//	insertId        unique identifier of the entry
+//	logName         full resource name of the log
+//	resource        monitored resource {type, labels}
+//	labels          user-defined labels
+//	trace, spanId   trace and span identifiers
+//	sourceLocation  {file, line, function}
+//	httpRequest     {requestMethod, requestUrl, status, latency, requestSize, responseSize, remoteIp, userAgent, referer}
+//	message         main message of the payload, without the other jsonPayload fields
+//	textPayload, jsonPayload or protoPayload (with its "@type")
+//	late            true when the entry arrived after newer ones were printed
+type record struct {
+	Timestamp      string            `json:"timestamp"`
+	Severity       string            `json:"severity"`
+	InsertID       string            `json:"insertId,omitempty"`
+	LogName        string            `json:"logName,omitempty"`
+	Resource       recordResource    `json:"resource"`
+	Labels         map[string]string `json:"labels,omitempty"`
+	Trace          string            `json:"trace,omitempty"`
+	SpanID         string            `json:"spanId,omitempty"`
+	SourceLocation *recordLocation   `json:"sourceLocation,omitempty"`
+	HTTPRequest    *recordRequest    `json:"httpRequest,omitempty"`
+	Message        string            `json:"message,omitempty"`
+	TextPayload    string            `json:"textPayload,omitempty"`
+	JSONPayload    map[string]any    `json:"jsonPayload,omitempty"`
+	ProtoPayload   map[string]any    `json:"protoPayload,omitempty"`
+	Late           bool              `json:"late,omitempty"`
+}
+
+type recordResource struct {
+	Type   string            `json:"type"`
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+type recordLocation struct {
+	File     string `json:"file,omitempty"`
+	Line     int64  `json:"line,omitempty"`
+	Function string `json:"function,omitempty"`
+}
+
+type recordRequest struct {
+	Method       string `json:"requestMethod,omitempty"`
+	URL          string `json:"requestUrl,omitempty"`
+	Status       int    `json:"status,omitempty"`
+	Latency      string `json:"latency,omitempty"`
+	RequestSize  int64  `json:"requestSize,omitempty"`
+	ResponseSize int64  `json:"responseSize,omitempty"`
+	RemoteIP     string `json:"remoteIp,omitempty"`
+	UserAgent    string `json:"userAgent,omitempty"`
+	Referer      string `json:"referer,omitempty"`
+}
+
+// suppressionEvent is the JSON representation of a Suppression, told apart from entries by its "event" key
+type suppressionEvent struct {
+	Event           string `json:"event"`
+	Timestamp       string `json:"timestamp"`
+	Reason          string `json:"reason"`
+	SuppressedCount int    `json:"suppressedCount"`
+}
+
+func newSuppressionEvent(suppression Suppression) *suppressionEvent {
+	return &suppressionEvent{
+		Event:           "suppression",
+		Timestamp:       time.Now().UTC().Format(time.RFC3339Nano),
+		Reason:          suppression.Reason,
+		SuppressedCount: suppression.Count,
+	}
+}
+
+// recordMessage returns the message of an entry in the machine-readable formats. The other fields of a jsonPayload
+// are already in the record, so only its message key is kept rather than the key=value line of the text output.
+func recordMessage(entry *Entry) string {
+	if entry.JSONPayload != nil {
+		_, message := jsonPayloadMessage(entry.JSONPayload)
+		return message
+	}
+
+	return entry.Message()
+}
+
+func newRecord(entry *Entry) *record {
+	r := &record{
+		Timestamp:    entry.Timestamp.Format(time.RFC3339Nano),
+		Severity:     entry.Severity,
+		InsertID:     entry.InsertID,
+		LogName:      entry.LogName,
+		Resource:     recordResource{Type: entry.Resource.Type, Labels: entry.Resource.Labels},
+		Labels:       entry.Labels,
+		Trace:        entry.Trace,
+		SpanID:       entry.SpanID,
+		Message:      recordMessage(entry),
+		TextPayload:  entry.TextPayload,
+		JSONPayload:  entry.JSONPayload,
+		ProtoPayload: entry.ProtoPayload,
+		Late:         entry.Late,
+	}
+
+	if loc := entry.SourceLocation; loc != nil {
+		r.SourceLocation = &recordLocation{File: loc.File, Line: loc.Line, Function: loc.Function}
+	}
+
+	if req := entry.HTTPRequest; req != nil {
+		r.HTTPRequest = &recordRequest{
+			Method:       req.Method,
+			URL:          req.URL,
+			Status:       req.Status,
+			Latency:      strconv.FormatFloat(req.Latency.Seconds(), 'f', -1, 64) + "s",
+			RequestSize:  req.RequestSize,
+			ResponseSize: req.ResponseSize,
+			RemoteIP:     req.RemoteIP,
+			UserAgent:    req.UserAgent,
+			Referer:      req.Referer,
+		}
+	}
+
+	return r
+}
+
+// textPrinter renders the human-readable "[timestamp] [SEVERITY] (resource) message" lines
+type textPrinter struct {
+	out            io.Writer
+	wide           bool
+	resourceLabels bool
+	projectPrefix  bool
+	// highlight is the pattern whose matches are highlighted in messages, nil to highlight nothing
+	highlight *regexp.Regexp
+}
+
+func (p *textPrinter) Print(entry *Entry) error {
+	return printEntry(p.out, entry, p.wide, p.resourceLabels, p.projectPrefix, p.highlight)
+}
+
+func (p *textPrinter) PrintSeparator() error {
+	if _, err := fmt.Fprintln(p.out, "--"); err != nil {
+		return fmt.Errorf("failed to write to output: \n%w", err)
+	}
+
+	return nil
+}
+
+func (p *textPrinter) Close() error {
+	return nil
+}
+
+// jsonPrinter renders all entries as a single indented JSON array
+type jsonPrinter struct {
+	out   io.Writer
+	count int
+}
+
+func (p *jsonPrinter) Print(entry *Entry) error {
+	return p.writeElement(newRecord(entry))
+}
+
+func (p *jsonPrinter) PrintSuppression(suppression Suppression) error {
+	return p.writeElement(newSuppressionEvent(suppression))
+}
+
+func (p *jsonPrinter) writeElement(element any) error {
+	encoded, err := json.MarshalIndent(element, "  ", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode entry: \n%w", err)
+	}
+
+	separator := ",\n  "
+	if p.count == 0 {
+		separator = "[\n  "
+	}
+	p.count++
+
+	if _, err := fmt.Fprintf(p.out, "%s%s", separator, encoded); err != nil {
+		return fmt.Errorf("failed to write to output: \n%w", err)
+	}
+
+	return nil
+}
+
+func (p *jsonPrinter) Close() error {
+	closing := "\n]\n"
+	if p.count == 0 {
+		closing = "[]\n"
+	}
+
+	if _, err := io.WriteString(p.out, closing); err != nil {
+		return fmt.Errorf("failed to write to output: \n%w", err)
+	}
+
+	return nil
+}
+
+// ndjsonPrinter renders one compact JSON object per line
+type ndjsonPrinter struct {
+	out io.Writer
+}
+
+func (p *ndjsonPrinter) Print(entry *Entry) error {
+	return p.writeLine(newRecord(entry))
+}
+
+func (p *ndjsonPrinter) PrintSuppression(suppression Suppression) error {
+	return p.writeLine(newSuppressionEvent(suppression))
+}
+
+func (p *ndjsonPrinter) writeLine(value any) error {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to encode entry: \n%w", err)
+	}
+
+	if _, err := fmt.Fprintf(p.out, "%s\n", encoded); err != nil {
+		return fmt.Errorf("failed to write to output: \n%w", err)
+	}
+
+	return nil
+}
+
+func (p *ndjsonPrinter) Close() error {
+	return nil
+}
+
+// logfmtLeadingKeys are printed first on every logfmt line, the other keys follow in alphabetical order
+var logfmtLeadingKeys = []string{"timestamp", "severity", "insertId", "logName", "resource.type", "message"}
+
+// logfmtPrinter renders one line of flattened key=value pairs per entry, nested keys are joined with dots
+type logfmtPrinter struct {
+	out io.Writer
+}
+
+func (p *logfmtPrinter) Print(entry *Entry) error {
+	fields, err := flattenRecord(newRecord(entry))
+	if err != nil {
+		return err
+	}
+
+	leading := make(map[string]bool, len(logfmtLeadingKeys))
+	keys := make([]string, 0, len(fields))
+	for _, key := range logfmtLeadingKeys {
+		leading[key] = true
+		if _, ok := fields[key]; ok {
+			keys = append(keys, key)
+		}
+	}
+
+	var rest []string
+	for key := range fields {
+		if !leading[key] {
+			rest = append(rest, key)
+		}
+	}
+	sort.Strings(rest)
+	keys = append(keys, rest...)
+
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		parts = append(parts, fmt.Sprintf("%s=%s", key, formatFieldValue(fields[key])))
+	}
+
+	if _, err := fmt.Fprintln(p.out, strings.Join(parts, " ")); err != nil {
+		return fmt.Errorf("failed to write to output: \n%w", err)
+	}
+
+	return nil
+}
+
+func (p *logfmtPrinter) Close() error {
+	return nil
+}
+
+// recordFields converts a record into its nested JSON fields
+func recordFields(r *record) (map[string]any, error) {
+	encoded, err := json.Marshal(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode entry: \n%w", err)
+	}
+
+	var fields map[string]any
+	if err := json.Unmarshal(encoded, &fields); err != nil {
+		return nil, fmt.Errorf("failed to encode entry: \n%w", err)
+	}
+
+	return fields, nil
+}
+
+// flattenRecord converts a record into a flat map whose nested keys are joined with dots
+func flattenRecord(r *record) (map[string]any, error) {
+	nested, err := recordFields(r)
+	if err != nil {
+		return nil, err
+	}
+
+	flat := make(map[string]any)
+	flattenInto(flat, "", nested)
+
+	return flat, nil
+}
+
+func flattenInto(flat map[string]any, prefix string, value any) {
+	object, ok := value.(map[string]any)
+	if !ok || (len(object) == 0 && prefix != "") {
+		flat[prefix] = value
+		return
+	}
+
+	for key, child := range object {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		flattenInto(flat, key, child)
+	}
+}
+
+// csvHeader lists the columns of the csv format, map and object columns are encoded as JSON
+var csvHeader = []string{
+	"timestamp", "severity", "insertId", "logName", "resourceType", "resourceLabels", "labels",
+	"trace", "spanId", "sourceLocation", "httpRequest", "message", "payload",
+}
+
+// csvPrinter renders a header row followed by one row per entry
+type csvPrinter struct {
+	writer        *csv.Writer
+	headerWritten bool
+}
+
+func (p *csvPrinter) Print(entry *Entry) error {
+	if !p.headerWritten {
+		if err := p.writer.Write(csvHeader); err != nil {
+			return fmt.Errorf("failed to write to output: \n%w", err)
+		}
+		p.headerWritten = true
+	}
+
+	r := newRecord(entry)
+
+	sourceLocation := ""
+	if loc := r.SourceLocation; loc != nil {
+		sourceLocation = fmt.Sprintf("%s:%d", loc.File, loc.Line)
+	}
+
+	var payload any
+	switch {
+	case r.JSONPayload != nil:
+		payload = r.JSONPayload
+	case r.ProtoPayload != nil:
+		payload = r.ProtoPayload
+	case r.TextPayload != "":
+		payload = r.TextPayload
+	}
+
+	row := []string{
+		r.Timestamp, r.Severity, r.InsertID, r.LogName, r.Resource.Type, encodeCSVValue(r.Resource.Labels), encodeCSVValue(r.Labels),
+		r.Trace, r.SpanID, sourceLocation, encodeCSVValue(r.HTTPRequest), r.Message, encodeCSVValue(payload),
+	}
+
+	if err := p.writer.Write(row); err != nil {
+		return fmt.Errorf("failed to write to output: \n%w", err)
+	}
+
+	// Flush every row so streamed entries show up immediately
+	p.writer.Flush()
+
+	return p.writer.Error()
+}
+
+func (p *csvPrinter) Close() error {
+	p.writer.Flush()
+	return p.writer.Error()
+}
+
+// encodeCSVValue encodes maps and objects as compact JSON, and leaves empty values blank
+func encodeCSVValue(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case map[string]string:
+		if len(v) == 0 {
+			return ""
+		}
+	case *recordRequest:
+		if v == nil {
+			return ""
+		}
+	}
+
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+
+	return string(encoded)
+}
@@ -0,0 +1,48 @@
+package stream
+
+import "testing"
+
+func TestRecordMessage(t *testing.T) {
+	tests := []struct {
+		name  string
+		entry *Entry
+		want  string
+		text  string
+	}{
+		{
+			name:  "jsonPayload with a message key",
+			entry: &Entry{JSONPayload: map[string]any{"message": " hello ", "k": "a b", "n": 3}},
+			want:  "hello",
+			text:  `hello k="a b" n=3`,
+		},
+		{
+			name:  "jsonPayload with a msg key",
+			entry: &Entry{JSONPayload: map[string]any{"msg": "hello", "message": ""}},
+			want:  "hello",
+			text:  `hello message=""`,
+		},
+		{
+			name:  "jsonPayload without a message key",
+			entry: &Entry{JSONPayload: map[string]any{"k": "v"}},
+			want:  "",
+			text:  "k=v",
+		},
+		{
+			name:  "textPayload",
+			entry: &Entry{TextPayload: "hello world\n"},
+			want:  "hello world",
+			text:  "hello world",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := newRecord(tt.entry).Message; got != tt.want {
+				t.Errorf("record message = %q, want %q", got, tt.want)
+			}
+			if got := tt.entry.Message(); got != tt.text {
+				t.Errorf("text message = %q, want %q", got, tt.text)
+			}
+		})
+	}
+}
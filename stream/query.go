@@ -0,0 +1,162 @@
+package stream
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Filter expressions are built as a small tree of nodes rendered to the logging query language,
+// so that values are always quoted and user expressions keep their meaning once combined.
+
+// Comparison operators of the logging query language
+const (
+	opEquals    = "="
+	opNotEquals = "!="
+	opLess      = "<"
+	opLessEq    = "<="
+	opGreater   = ">"
+	opGreaterEq = ">="
+	opHas       = ":"
+	opRegex     = "=~"
+	opNotRegex  = "!~"
+)
+
+// expr is a node of a filter expression
+type expr interface {
+	String() string
+}
+
+// comparison compares a field with a value, e.g. resource.type = "k8s_container"
+type comparison struct {
+	field string
+	op    string
+	value string
+}
+
+func (c comparison) String() string {
+	return c.field + " " + c.op + " " + quoteString(c.value)
+}
+
+// has matches a field containing a value, e.g. textPayload:"timeout"
+type has struct {
+	field string
+	value string
+}
+
+func (h has) String() string {
+	return h.field + ":" + quoteString(h.value)
+}
+
+// exists matches the entries where a field is set, e.g. labels.env:*
+type exists struct {
+	field string
+}
+
+func (e exists) String() string {
+	return e.field + ":*"
+}
+
+// call calls a function with string arguments, e.g. SEARCH("timeout")
+type call struct {
+	name string
+	args []string
+}
+
+func (c call) String() string {
+	args := make([]string, 0, len(c.args))
+	for _, arg := range c.args {
+		args = append(args, quoteString(arg))
+	}
+
+	return c.name + "(" + strings.Join(args, ", ") + ")"
+}
+
+// and matches the entries matching all of its expressions
+type and []expr
+
+func (a and) String() string {
+	return joinExprs(a, " AND ")
+}
+
+// or matches the entries matching any of its expressions, it is parenthesized when it has several expressions
+type or []expr
+
+func (o or) String() string {
+	if len(o) == 1 {
+		return o[0].String()
+	}
+
+	return "(" + joinExprs(o, " OR ") + ")"
+}
+
+// group parenthesizes an expression, e.g. an AND nested in an OR
+type group struct {
+	expr expr
+}
+
+func (g group) String() string {
+	// Expressions that are already parenthesized are not wrapped again
+	switch e := g.expr.(type) {
+	case group, userExpr:
+		return e.String()
+	case or:
+		if len(e) > 1 {
+			return e.String()
+		}
+	}
+
+	return "(" + g.expr.String() + ")"
+}
+
+// not negates an expression
+type not struct {
+	expr expr
+}
+
+func (n not) String() string {
+	switch n.expr.(type) {
+	case or, group, userExpr:
+		return "NOT " + n.expr.String()
+	default:
+		return "NOT (" + n.expr.String() + ")"
+	}
+}
+
+// userExpr is an expression written by the user, parenthesized so that it keeps its meaning once combined.
+// The parentheses are on their own lines, so that a trailing -- comment does not comment out the closing one.
+type userExpr string
+
+func (u userExpr) String() string {
+	return "(\n" + string(u) + "\n)"
+}
+
+func joinExprs(exprs []expr, separator string) string {
+	parts := make([]string, 0, len(exprs))
+	for _, e := range exprs {
+		parts = append(parts, e.String())
+	}
+
+	return strings.Join(parts, separator)
+}
+
+// identifierPattern matches the field path segments that do not need quoting
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// field joins the segments of a field path, quoting the segments that are not plain identifiers,
+// e.g. field("labels", "app.kubernetes.io/name") gives labels."app.kubernetes.io/name"
+func field(segments ...string) string {
+	quoted := make([]string, 0, len(segments))
+	for _, segment := range segments {
+		if !identifierPattern.MatchString(segment) {
+			segment = quoteString(segment)
+		}
+		quoted = append(quoted, segment)
+	}
+
+	return strings.Join(quoted, ".")
+}
+
+// quoteString returns s as a double-quoted string of the query language, escaping backslashes and double quotes
+func quoteString(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
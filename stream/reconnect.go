@@ -0,0 +1,66 @@
+package stream
+
+import (
+	"errors"
+	"math/rand/v2"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	initialBackoff = time.Second
+	maxBackoff     = 30 * time.Second
+)
+
+var (
+	// errLimitReached stops printing once the requested number of entries has been printed
+	errLimitReached = errors.New("limit reached")
+	// errSessionEnded reports a stream closed by the server, TailLogEntries sessions end periodically
+	errSessionEnded = errors.New("session ended by the server")
+)
+
+// isRetryable reports whether a stream error is transient and the stream should be reopened
+func isRetryable(err error) bool {
+	if errors.Is(err, errSessionEnded) {
+		return true
+	}
+
+	switch status.Code(err) {
+	case codes.Unavailable, codes.ResourceExhausted, codes.Internal, codes.DeadlineExceeded, codes.Aborted:
+		return true
+	}
+
+	return false
+}
+
+// backoff returns the delay before a reconnection attempt, an exponential backoff with jitter capped at maxBackoff
+func backoff(attempt int) time.Duration {
+	delay := maxBackoff
+	if attempt < 5 {
+		delay = min(initialBackoff<<attempt, maxBackoff)
+	}
+
+	// Wait between half and all of the delay so that concurrent clients do not reconnect in lockstep
+	return delay/2 + rand.N(delay/2+1)
+}
+
+// describeStreamError returns a short description of a stream error for reconnection notices
+func describeStreamError(err error) string {
+	if errors.Is(err, errSessionEnded) {
+		return errSessionEnded.Error()
+	}
+
+	return status.Code(err).String()
+}
+
+// resumeFilter restricts a filter to the entries at or after a point in time
+func resumeFilter(filter string, from time.Time) string {
+	clauses := and{comparison{"timestamp", opGreaterEq, from.Format(time.RFC3339Nano)}}
+	if filter != "" {
+		clauses = and{userExpr(filter), clauses[0]}
+	}
+
+	return clauses.String()
+}
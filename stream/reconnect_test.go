@@ -0,0 +1,50 @@
+package stream
+
+import (
+	"testing"
+	"time"
+)
+
+func TestResumeFilter(t *testing.T) {
+	from := time.Date(2026, 10, 15, 9, 30, 0, 123456789, time.UTC)
+
+	tests := []struct {
+		name   string
+		filter string
+		want   string
+	}{
+		{
+			name:   "no filter",
+			filter: "",
+			want:   `timestamp >= "2026-10-15T09:30:00.123456789Z"`,
+		},
+		{
+			name:   "filter",
+			filter: `severity >= "ERROR"`,
+			want:   "(\nseverity >= \"ERROR\"\n) AND timestamp >= \"2026-10-15T09:30:00.123456789Z\"",
+		},
+		{
+			name:   "OR filter",
+			filter: `resource.type = "gce_instance" OR resource.type = "gae_app"`,
+			want:   "(\nresource.type = \"gce_instance\" OR resource.type = \"gae_app\"\n) AND timestamp >= \"2026-10-15T09:30:00.123456789Z\"",
+		},
+		{
+			name:   "filter ending with a comment",
+			filter: "severity >= \"ERROR\" -- only errors",
+			want:   "(\nseverity >= \"ERROR\" -- only errors\n) AND timestamp >= \"2026-10-15T09:30:00.123456789Z\"",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := resumeFilter(tt.filter, from)
+			if got != tt.want {
+				t.Errorf("resumeFilter(%q) =\n%s\nwant\n%s", tt.filter, got, tt.want)
+			}
+
+			if _, err := ParseQuery(got); err != nil {
+				t.Errorf("resumeFilter(%q) does not parse: %v", tt.filter, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package stream
+
+import "time"
+
+// minReorderTick is the shortest interval at which the reorder buffer releases entries
+const minReorderTick = 50 * time.Millisecond
+
+// heldEntry is an entry held by the reorder buffer with the time it was received
+type heldEntry struct {
+	entry   *Entry
+	arrival time.Time
+}
+
+// reorderBuffer holds live entries for a window so that they are released in timestamp order.
+// Entries arriving after a newer entry was released are flagged as late instead of being dropped.
+type reorderBuffer struct {
+	window  time.Duration
+	pending []heldEntry
+	// last is the newest entry released so far
+	last *Entry
+}
+
+func newReorderBuffer(window time.Duration) *reorderBuffer {
+	return &reorderBuffer{window: window}
+}
+
+// tick returns the interval at which the buffer should be released
+func (b *reorderBuffer) tick() time.Duration {
+	return max(b.window/4, minReorderTick)
+}
+
+func (b *reorderBuffer) add(entry *Entry, now time.Time) {
+	if b.last != nil && entryLess(entry, b.last) {
+		entry.Late = true
+	}
+
+	b.pending = append(b.pending, heldEntry{entry: entry, arrival: now})
+}
+
+// release returns, in order, the entries held for the whole window and the pending entries sorting before them
+func (b *reorderBuffer) release(now time.Time) []*Entry {
+	var newest *Entry
+	for _, held := range b.pending {
+		if now.Sub(held.arrival) >= b.window && (newest == nil || entryLess(newest, held.entry)) {
+			newest = held.entry
+		}
+	}
+
+	if newest == nil {
+		return nil
+	}
+
+	var released []*Entry
+	kept := b.pending[:0]
+	for _, held := range b.pending {
+		if entryLess(newest, held.entry) {
+			kept = append(kept, held)
+			continue
+		}
+		released = append(released, held.entry)
+	}
+	b.pending = kept
+
+	return b.sorted(released)
+}
+
+// flush returns all the pending entries in order
+func (b *reorderBuffer) flush() []*Entry {
+	released := make([]*Entry, 0, len(b.pending))
+	for _, held := range b.pending {
+		released = append(released, held.entry)
+	}
+	b.pending = nil
+
+	return b.sorted(released)
+}
+
+func (b *reorderBuffer) sorted(entries []*Entry) []*Entry {
+	sortEntries(entries)
+
+	if n := len(entries); n > 0 && (b.last == nil || entryLess(b.last, entries[n-1])) {
+		b.last = entries[n-1]
+	}
+
+	return entries
+}
@@ -0,0 +1,147 @@
+package stream
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// reorderStep adds an entry to the buffer, or releases or flushes it, at a time relative to the start of the test
+type reorderStep struct {
+	at    time.Duration
+	add   *Entry
+	flush bool
+	// want lists the insertIds released by the step, late entries end with "!"
+	want []string
+}
+
+var reorderStart = time.Date(2026, 10, 15, 9, 30, 0, 0, time.UTC)
+
+// reorderEntry returns an entry written at an offset from the start of the test
+func reorderEntry(insertID string, offset time.Duration) *Entry {
+	return &Entry{InsertID: insertID, Timestamp: reorderStart.Add(offset)}
+}
+
+func TestReorderBuffer(t *testing.T) {
+	tests := []struct {
+		name  string
+		steps []reorderStep
+	}{
+		{
+			name: "entries are held for the window",
+			steps: []reorderStep{
+				{at: 0, add: reorderEntry("a", time.Second)},
+				{at: time.Second},
+				{at: 1999 * time.Millisecond},
+				{at: 2 * time.Second, want: []string{"a"}},
+				{at: 3 * time.Second},
+			},
+		},
+		{
+			name: "entries sorting before a released entry are released early",
+			steps: []reorderStep{
+				{at: 0, add: reorderEntry("c", 3*time.Second)},
+				{at: 500 * time.Millisecond, add: reorderEntry("a", time.Second)},
+				{at: time.Second, add: reorderEntry("b", 2*time.Second)},
+				{at: 2 * time.Second, want: []string{"a", "b", "c"}},
+			},
+		},
+		{
+			name: "newer entries wait for their own window",
+			steps: []reorderStep{
+				{at: 0, add: reorderEntry("a", time.Second)},
+				{at: 1500 * time.Millisecond, add: reorderEntry("d", 4*time.Second)},
+				{at: 2 * time.Second, want: []string{"a"}},
+				{at: 3 * time.Second},
+				{at: 3500 * time.Millisecond, want: []string{"d"}},
+			},
+		},
+		{
+			name: "equal timestamps are ordered by insertId",
+			steps: []reorderStep{
+				{at: 0, add: reorderEntry("b", time.Second)},
+				{at: 0, add: reorderEntry("a", time.Second)},
+				{at: 2 * time.Second, want: []string{"a", "b"}},
+			},
+		},
+		{
+			name: "entries older than the last released entry are late",
+			steps: []reorderStep{
+				{at: 0, add: reorderEntry("b", 2*time.Second)},
+				{at: 2 * time.Second, want: []string{"b"}},
+				{at: 2500 * time.Millisecond, add: reorderEntry("a", time.Second)},
+				{at: 2500 * time.Millisecond, add: reorderEntry("c", 3*time.Second)},
+				{at: 4500 * time.Millisecond, want: []string{"a!", "c"}},
+			},
+		},
+		{
+			name: "flush releases every pending entry in order",
+			steps: []reorderStep{
+				{at: 0, add: reorderEntry("c", 3*time.Second)},
+				{at: 100 * time.Millisecond, add: reorderEntry("a", time.Second)},
+				{at: 200 * time.Millisecond, flush: true, want: []string{"a", "c"}},
+				{at: 3 * time.Second},
+			},
+		},
+		{
+			name: "a flushed entry makes older arrivals late",
+			steps: []reorderStep{
+				{at: 0, add: reorderEntry("b", 2*time.Second)},
+				{at: 100 * time.Millisecond, flush: true, want: []string{"b"}},
+				{at: 200 * time.Millisecond, add: reorderEntry("a", time.Second)},
+				{at: 300 * time.Millisecond, flush: true, want: []string{"a!"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buffer := newReorderBuffer(2 * time.Second)
+
+			for i, step := range tt.steps {
+				now := reorderStart.Add(step.at)
+
+				var released []*Entry
+				switch {
+				case step.add != nil:
+					buffer.add(step.add, now)
+				case step.flush:
+					released = buffer.flush()
+				default:
+					released = buffer.release(now)
+				}
+
+				got := make([]string, 0, len(released))
+				for _, entry := range released {
+					id := entry.InsertID
+					if entry.Late {
+						id += "!"
+					}
+					got = append(got, id)
+				}
+
+				if fmt.Sprint(got) != fmt.Sprint(step.want) {
+					t.Errorf("step %d at %s released %q, want %q", i, step.at, got, step.want)
+				}
+			}
+		})
+	}
+}
+
+func TestReorderBufferTick(t *testing.T) {
+	tests := []struct {
+		window time.Duration
+		want   time.Duration
+	}{
+		{window: 2 * time.Second, want: 500 * time.Millisecond},
+		{window: 100 * time.Millisecond, want: minReorderTick},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.window.String(), func(t *testing.T) {
+			if got := newReorderBuffer(tt.window).tick(); got != tt.want {
+				t.Errorf("tick() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
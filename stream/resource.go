@@ -0,0 +1,80 @@
+package stream
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// resourceDisplayLabels lists, for each resource type, the labels identifying a resource in the line prefix.
+// Resource presets register the display labels of their own resource types.
+var resourceDisplayLabels = map[string][]string{
+	"k8s_container":      {"namespace_name", "pod_name", "container_name"},
+	"k8s_pod":            {"namespace_name", "pod_name"},
+	"k8s_node":           {"node_name"},
+	"k8s_cluster":        {"cluster_name"},
+	"cloud_run_job":      {"job_name"},
+	"cloudsql_database":  {"database_id"},
+	"gcs_bucket":         {"bucket_name"},
+	"http_load_balancer": {"forwarding_rule_name"},
+}
+
+// formatResource renders the resource type followed by its key labels, e.g. "k8s_container default/api-7d9f/server"
+func formatResource(entry *Entry) string {
+	var values []string
+	for _, label := range resourceDisplayLabels[entry.Resource.Type] {
+		if value := entry.Resource.Labels[label]; value != "" {
+			values = append(values, value)
+		}
+	}
+
+	if len(values) == 0 {
+		return entry.Resource.Type
+	}
+
+	return fmt.Sprintf("%s %s", entry.Resource.Type, strings.Join(values, "/"))
+}
+
+// formatWideDetails renders the short log name, trace/span IDs and source location of an entry when present
+func formatWideDetails(entry *Entry) string {
+	var details []string
+
+	if logName := shortLogName(entry.LogName); logName != "" {
+		details = append(details, logName)
+	}
+
+	if entry.Trace != "" {
+		details = append(details, "trace="+shortTrace(entry.Trace))
+	}
+
+	if entry.SpanID != "" {
+		details = append(details, "span="+entry.SpanID)
+	}
+
+	if loc := entry.SourceLocation; loc != nil && loc.File != "" {
+		details = append(details, fmt.Sprintf("%s:%d", loc.File, loc.Line))
+	}
+
+	return strings.Join(details, " ")
+}
+
+// shortLogName returns the log ID of a log name, e.g. "cloudaudit.googleapis.com/activity"
+// for "projects/p/logs/cloudaudit.googleapis.com%2Factivity"
+func shortLogName(logName string) string {
+	_, logID, found := strings.Cut(logName, "/logs/")
+	if !found {
+		return logName
+	}
+
+	unescaped, err := url.PathUnescape(logID)
+	if err != nil {
+		return logID
+	}
+
+	return unescaped
+}
+
+// shortTrace returns the trace ID of a trace resource name, e.g. "abc123" for "projects/p/traces/abc123"
+func shortTrace(trace string) string {
+	return trace[strings.LastIndex(trace, "/")+1:]
+}
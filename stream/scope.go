@@ -0,0 +1,83 @@
+package stream
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Kinds of scopes
+const (
+	ScopeProject        = "projects"
+	ScopeOrganization   = "organizations"
+	ScopeFolder         = "folders"
+	ScopeBillingAccount = "billingAccounts"
+	ScopeView           = "views"
+)
+
+var (
+	scopeIDPattern   = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._:-]*$`)
+	scopeViewPattern = regexp.MustCompile(`^projects/[^/]+/locations/[^/]+/buckets/[^/]+/views/[^/]+$`)
+)
+
+// Scope is a resource whose logs are read: a project, an organization, a folder, a billing account or a log bucket view
+type Scope struct {
+	Kind string
+	// Name is the full resource name, e.g. "projects/my-project", "organizations/123" or
+	// "projects/my-project/locations/global/buckets/my-bucket/views/my-view"
+	Name string
+}
+
+// NewScope returns the scope of the given kind (projects, organizations, folders or billingAccounts) and ID
+func NewScope(kind string, id string) (Scope, error) {
+	switch kind {
+	case ScopeProject, ScopeOrganization, ScopeFolder, ScopeBillingAccount:
+	default:
+		return Scope{}, fmt.Errorf("unknown scope kind: %q", kind)
+	}
+
+	if !scopeIDPattern.MatchString(id) {
+		return Scope{}, fmt.Errorf("invalid %s ID: %q", strings.TrimSuffix(kind, "s"), id)
+	}
+
+	return Scope{Kind: kind, Name: kind + "/" + id}, nil
+}
+
+// ParseScope parses a project ID or a full resource name such as "organizations/123", "folders/456",
+// "billingAccounts/0123AB-4567CD-89EF01" or "projects/p/locations/l/buckets/b/views/v"
+func ParseScope(name string) (Scope, error) {
+	if scopeViewPattern.MatchString(name) {
+		return Scope{Kind: ScopeView, Name: name}, nil
+	}
+
+	kind, id, found := strings.Cut(name, "/")
+	if !found {
+		return NewScope(ScopeProject, name)
+	}
+
+	scope, err := NewScope(kind, id)
+	if err != nil {
+		return Scope{}, fmt.Errorf("invalid scope %q (expected a project ID, projects/ID, organizations/ID, folders/ID, billingAccounts/ID or projects/p/locations/l/buckets/b/views/v): \n%w", name, err)
+	}
+
+	return scope, nil
+}
+
+// String returns the project ID of project scopes and the full resource name of other scopes
+func (s Scope) String() string {
+	if s.Kind == ScopeProject {
+		return strings.TrimPrefix(s.Name, ScopeProject+"/")
+	}
+
+	return s.Name
+}
+
+// resourceNames returns the resource names of the scopes
+func resourceNames(scopes []Scope) []string {
+	names := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		names = append(names, scope.Name)
+	}
+
+	return names
+}
@@ -0,0 +1,92 @@
+package stream
+
+import "testing"
+
+func TestParseScope(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Scope
+		display string
+		wantErr bool
+	}{
+		{input: "my-project", want: Scope{Kind: ScopeProject, Name: "projects/my-project"}, display: "my-project"},
+		{input: "projects/my-project", want: Scope{Kind: ScopeProject, Name: "projects/my-project"}, display: "my-project"},
+		{input: "example.com:my-project", want: Scope{Kind: ScopeProject, Name: "projects/example.com:my-project"}, display: "example.com:my-project"},
+		{input: "organizations/123456789012", want: Scope{Kind: ScopeOrganization, Name: "organizations/123456789012"}, display: "organizations/123456789012"},
+		{input: "folders/345678901234", want: Scope{Kind: ScopeFolder, Name: "folders/345678901234"}, display: "folders/345678901234"},
+		{input: "billingAccounts/0123AB-4567CD-89EF01", want: Scope{Kind: ScopeBillingAccount, Name: "billingAccounts/0123AB-4567CD-89EF01"}, display: "billingAccounts/0123AB-4567CD-89EF01"},
+		{
+			input:   "projects/sec-logs/locations/global/buckets/org-audit/views/_AllLogs",
+			want:    Scope{Kind: ScopeView, Name: "projects/sec-logs/locations/global/buckets/org-audit/views/_AllLogs"},
+			display: "projects/sec-logs/locations/global/buckets/org-audit/views/_AllLogs",
+		},
+
+		{input: "", wantErr: true},
+		{input: "-project", wantErr: true},
+		{input: "my project", wantErr: true},
+		{input: "teams/123", wantErr: true},
+		{input: "organizations/", wantErr: true},
+		{input: "folders/12/34", wantErr: true},
+		{input: "projects/p/locations/global/buckets/b", wantErr: true},
+		{input: "projects/p/locations/global/buckets/b/views/", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseScope(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseScope(%q) = %+v, want an error", tt.input, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseScope(%q) returned an error: %v", tt.input, err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseScope(%q) = %+v, want %+v", tt.input, got, tt.want)
+			}
+			if got.String() != tt.display {
+				t.Errorf("ParseScope(%q).String() = %q, want %q", tt.input, got.String(), tt.display)
+			}
+		})
+	}
+}
+
+func TestNewScope(t *testing.T) {
+	tests := []struct {
+		kind    string
+		id      string
+		want    string
+		wantErr bool
+	}{
+		{kind: ScopeOrganization, id: "123456789012", want: "organizations/123456789012"},
+		{kind: ScopeFolder, id: "345678901234", want: "folders/345678901234"},
+		{kind: ScopeBillingAccount, id: "0123AB-4567CD-89EF01", want: "billingAccounts/0123AB-4567CD-89EF01"},
+		{kind: ScopeProject, id: "my-project", want: "projects/my-project"},
+
+		{kind: ScopeView, id: "v", wantErr: true},
+		{kind: "teams", id: "123", wantErr: true},
+		{kind: ScopeOrganization, id: "", wantErr: true},
+		{kind: ScopeFolder, id: "12/34", wantErr: true},
+		{kind: ScopeBillingAccount, id: "abc def", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.kind+"/"+tt.id, func(t *testing.T) {
+			got, err := NewScope(tt.kind, tt.id)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("NewScope(%q, %q) = %+v, want an error", tt.kind, tt.id, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewScope(%q, %q) returned an error: %v", tt.kind, tt.id, err)
+			}
+			if got.Name != tt.want || got.Kind != tt.kind {
+				t.Errorf("NewScope(%q, %q) = %+v, want kind %q and name %q", tt.kind, tt.id, got, tt.kind, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package stream
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Label selector operators
+const (
+	SelectorEquals       = "="
+	SelectorNotEquals    = "!="
+	SelectorIn           = "in"
+	SelectorNotIn        = "notin"
+	SelectorExists       = "exists"
+	SelectorDoesNotExist = "!"
+)
+
+// LabelRequirement is a single requirement of a kubectl-style label selector, e.g. "team in (payments,ledger)"
+type LabelRequirement struct {
+	Key      string
+	Operator string
+	Values   []string
+}
+
+var (
+	selectorKeyPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._/-]*$`)
+	selectorSetPattern = regexp.MustCompile(`^(\S+)\s+(in|notin)\s*\((.*)\)$`)
+)
+
+// ParseSelector parses a kubectl-style label selector such as "env=prod,tier!=cache,team in (payments,ledger)".
+// Supported requirements are key=value, key==value, key!=value, key in (a,b), key notin (a,b), key and !key.
+func ParseSelector(selector string) ([]LabelRequirement, error) {
+	var requirements []LabelRequirement
+
+	for _, part := range splitSelector(selector) {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			return nil, fmt.Errorf("empty requirement in selector %q", selector)
+		}
+
+		requirement, err := parseRequirement(part)
+		if err != nil {
+			return nil, err
+		}
+
+		if !selectorKeyPattern.MatchString(requirement.Key) {
+			return nil, fmt.Errorf("invalid label key %q in requirement %q", requirement.Key, part)
+		}
+
+		requirements = append(requirements, requirement)
+	}
+
+	return requirements, nil
+}
+
+// splitSelector splits a selector on the commas that are not inside a set of values
+func splitSelector(selector string) []string {
+	var parts []string
+
+	depth, start := 0, 0
+	for i, r := range selector {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, selector[start:i])
+				start = i + 1
+			}
+		}
+	}
+
+	return append(parts, selector[start:])
+}
+
+func parseRequirement(part string) (LabelRequirement, error) {
+	if match := selectorSetPattern.FindStringSubmatch(part); match != nil {
+		var values []string
+		for _, value := range strings.Split(match[3], ",") {
+			value = strings.TrimSpace(value)
+			if value == "" {
+				return LabelRequirement{}, fmt.Errorf("empty value in requirement %q", part)
+			}
+			values = append(values, value)
+		}
+
+		return LabelRequirement{Key: match[1], Operator: match[2], Values: values}, nil
+	}
+
+	if key, value, found := strings.Cut(part, "!="); found {
+		return LabelRequirement{Key: strings.TrimSpace(key), Operator: SelectorNotEquals, Values: []string{strings.TrimSpace(value)}}, nil
+	}
+
+	if key, value, found := strings.Cut(part, "=="); found {
+		return LabelRequirement{Key: strings.TrimSpace(key), Operator: SelectorEquals, Values: []string{strings.TrimSpace(value)}}, nil
+	}
+
+	if key, value, found := strings.Cut(part, "="); found {
+		return LabelRequirement{Key: strings.TrimSpace(key), Operator: SelectorEquals, Values: []string{strings.TrimSpace(value)}}, nil
+	}
+
+	if key, found := strings.CutPrefix(part, "!"); found {
+		return LabelRequirement{Key: strings.TrimSpace(key), Operator: SelectorDoesNotExist}, nil
+	}
+
+	if strings.ContainsAny(part, " ()") {
+		return LabelRequirement{}, fmt.Errorf("invalid requirement %q (expected key=value, key!=value, key in (a,b), key notin (a,b), key or !key)", part)
+	}
+
+	return LabelRequirement{Key: part, Operator: SelectorExists}, nil
+}
+
+// expr returns the filter clause matching the requirement against both entry labels and resource labels
+func (r LabelRequirement) expr() expr {
+	// Label keys that are not plain identifiers are quoted, e.g. labels."app.kubernetes.io/name"
+	fields := []string{field("labels", r.Key), field("resource", "labels", r.Key)}
+
+	var matches or
+	for _, f := range fields {
+		if r.Operator == SelectorExists || r.Operator == SelectorDoesNotExist {
+			matches = append(matches, exists{f})
+			continue
+		}
+
+		for _, value := range r.Values {
+			matches = append(matches, comparison{f, opEquals, value})
+		}
+	}
+
+	// A single match is still parenthesized, like the other requirements
+	var clause expr = matches
+	if len(matches) == 1 {
+		clause = group{matches[0]}
+	}
+
+	switch r.Operator {
+	case SelectorNotEquals, SelectorNotIn, SelectorDoesNotExist:
+		return not{clause}
+	default:
+		return clause
+	}
+}
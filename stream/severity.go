@@ -0,0 +1,60 @@
+package stream
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// severityLevels maps the Cloud Logging severities to their numeric level, in increasing order of severity
+var severityLevels = map[string]int{
+	"DEFAULT":   0,
+	"DEBUG":     100,
+	"INFO":      200,
+	"NOTICE":    300,
+	"WARNING":   400,
+	"ERROR":     500,
+	"CRITICAL":  600,
+	"ALERT":     700,
+	"EMERGENCY": 800,
+}
+
+// severityAliases maps common severity names used by logging libraries to Cloud Logging severities
+var severityAliases = map[string]string{
+	"TRACE":       "DEBUG",
+	"INFORMATION": "INFO",
+	"WARN":        "WARNING",
+	"ERR":         "ERROR",
+	"CRIT":        "CRITICAL",
+	"FATAL":       "CRITICAL",
+	"PANIC":       "EMERGENCY",
+	"EMERG":       "EMERGENCY",
+}
+
+// ParseSeverity normalizes a severity name, alias (e.g. WARN, FATAL) or numeric level (e.g. 400) to a Cloud Logging severity
+func ParseSeverity(severity string) (string, error) {
+	upper := strings.ToUpper(strings.TrimSpace(severity))
+
+	if _, ok := severityLevels[upper]; ok {
+		return upper, nil
+	}
+
+	if alias, ok := severityAliases[upper]; ok {
+		return alias, nil
+	}
+
+	if level, err := strconv.Atoi(upper); err == nil {
+		for name, value := range severityLevels {
+			if value == level {
+				return name, nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("unknown severity %q", severity)
+}
+
+// SeverityLevel returns the numeric level of a Cloud Logging severity, unknown severities are treated as DEFAULT
+func SeverityLevel(severity string) int {
+	return severityLevels[strings.ToUpper(severity)]
+}
@@ -0,0 +1,74 @@
+package stream
+
+import "testing"
+
+func TestParseSeverity(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{input: "ERROR", want: "ERROR"},
+		{input: "error", want: "ERROR"},
+		{input: " Warning ", want: "WARNING"},
+		{input: "DEFAULT", want: "DEFAULT"},
+		{input: "EMERGENCY", want: "EMERGENCY"},
+
+		// Aliases of logging libraries
+		{input: "warn", want: "WARNING"},
+		{input: "err", want: "ERROR"},
+		{input: "trace", want: "DEBUG"},
+		{input: "information", want: "INFO"},
+		{input: "crit", want: "CRITICAL"},
+		{input: "fatal", want: "CRITICAL"},
+		{input: "panic", want: "EMERGENCY"},
+		{input: "emerg", want: "EMERGENCY"},
+
+		// Numeric levels
+		{input: "0", want: "DEFAULT"},
+		{input: "400", want: "WARNING"},
+		{input: "800", want: "EMERGENCY"},
+
+		{input: "", wantErr: true},
+		{input: "verbose", wantErr: true},
+		{input: "450", wantErr: true},
+		{input: "-100", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseSeverity(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseSeverity(%q) = %q, want an error", tt.input, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseSeverity(%q) returned an error: %v", tt.input, err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseSeverity(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSeverityLevel(t *testing.T) {
+	tests := []struct {
+		severity string
+		want     int
+	}{
+		{severity: "DEFAULT", want: 0},
+		{severity: "debug", want: 100},
+		{severity: "WARNING", want: 400},
+		{severity: "EMERGENCY", want: 800},
+		{severity: "UNKNOWN", want: 0},
+	}
+
+	for _, tt := range tests {
+		if got := SeverityLevel(tt.severity); got != tt.want {
+			t.Errorf("SeverityLevel(%q) = %d, want %d", tt.severity, got, tt.want)
+		}
+	}
+}
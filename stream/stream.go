@@ -5,40 +5,58 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"maps"
 	"os"
 	"os/signal"
+	"regexp"
+	"slices"
 	"strings"
 	"syscall"
 	"time"
 
-	"cloud.google.com/go/logging"
 	loggingv2 "cloud.google.com/go/logging/apiv2"
 	"cloud.google.com/go/logging/apiv2/loggingpb"
-	"cloud.google.com/go/logging/logadmin"
 	"google.golang.org/api/iterator"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
 
-func printLogEntry(out io.Writer, entry *logging.Entry) error {
+// printEntry renders an entry as "[timestamp] [SEVERITY] (resource) message" lines,
+// one for its HTTP request when present and one for its payload.
+// With resourceLabels the resource also shows its key labels,
+// and wide mode adds the short log name, trace/span and source location.
+// With projectPrefix every line starts with the project of the entry, and highlight marks its matches in the message.
+func printEntry(out io.Writer, entry *Entry, wide bool, resourceLabels bool, projectPrefix bool, highlight *regexp.Regexp) error {
 	timestamp := entry.Timestamp.Format(time.RFC3339)
-	severity := formatSeverity(entry.Severity.String())
-	resourceType := entry.Resource.Type
+	severity := formatSeverity(entry.Severity)
+	resource := entry.Resource.Type
 
-	if req := entry.HTTPRequest; req != nil {
-		reqUrl := ""
-		if req.Request != nil {
-			reqUrl = req.Request.URL.String()
+	if resourceLabels {
+		resource = formatResource(entry)
+	}
+
+	prefix := fmt.Sprintf("[%v] [%s] (%s)", timestamp, severity, resource)
+	if projectPrefix {
+		prefix = formatProject(entry.Project()) + " " + prefix
+	}
+	if entry.Late {
+		prefix += " [late]"
+	}
+	if wide {
+		if details := formatWideDetails(entry); details != "" {
+			prefix += " " + details
 		}
-		_, err := fmt.Fprintf(out, "[%v] [%s] (%s) %s %s %d %dms\n", timestamp, severity, resourceType, req.Request.Method, reqUrl, req.Status, req.Latency.Milliseconds())
+	}
+
+	if req := entry.HTTPRequest; req != nil {
+		_, err := fmt.Fprintf(out, "%s %s %s %d %dms\n", prefix, req.Method, req.URL, req.Status, req.Latency.Milliseconds())
 		if err != nil {
 			return fmt.Errorf("failed to write to output: \n%w", err)
 		}
 	}
 
-	if payload, ok := entry.Payload.(string); ok {
-		trimmed := strings.TrimSpace(payload)
-		_, err := fmt.Fprintf(out, "[%v] [%s] (%s) %s\n", timestamp, severity, resourceType, trimmed)
+	if message := entry.Message(); message != "" {
+		_, err := fmt.Fprintf(out, "%s %s\n", prefix, highlightMatches(message, highlight))
 		if err != nil {
 			return fmt.Errorf("failed to write to output: \n%w", err)
 		}
@@ -47,44 +65,48 @@ func printLogEntry(out io.Writer, entry *logging.Entry) error {
 	return nil
 }
 
-func printTailLogEntry(out io.Writer, entry *loggingpb.LogEntry) error {
-	timestamp := entry.Timestamp.AsTime().Format(time.RFC3339)
-	severity := formatSeverity(entry.Severity.String())
-	resourceType := entry.Resource.Type
+// Orders of the historical entries
+const (
+	OrderAsc  = "asc"
+	OrderDesc = "desc"
+)
 
-	if req := entry.HttpRequest; req != nil {
-		_, err := fmt.Fprintf(out, "[%v] [%s] (%s) %s %s %d %dms\n", timestamp, severity, resourceType, req.RequestMethod, req.RequestUrl, req.Status, req.Latency.AsDuration().Milliseconds())
-		if err != nil {
-			return fmt.Errorf("failed to write to output: \n%w", err)
-		}
+// GetEntries fetches and list log entries according to a filter.
+// With a limit, the newest entries are fetched and printed in the requested order (oldest first with OrderAsc, like tail -n).
+// Entries of several scopes are merged in timestamp order.
+// With a checkpoint, the entries it already holds are skipped and the printed entries are recorded.
+func GetEntries(printer Printer, scopes []Scope, filter string, limit int, order string, checkpoint *Checkpoint) error {
+	if checkpoint != nil {
+		printer = checkpointPrinter(printer, checkpoint)
 	}
 
-	if payload := entry.GetTextPayload(); payload != "" {
-		trimmed := strings.TrimSpace(payload)
-		_, err := fmt.Fprintf(out, "[%v] [%s] (%s) %s\n", timestamp, severity, resourceType, trimmed)
-		if err != nil {
-			return fmt.Errorf("failed to write to output: \n%w", err)
-		}
+	counter, err := fetchEntries(context.Background(), printer, scopes, filter, limit, order)
+	if err != nil {
+		return err
+	}
+
+	if err := checkpoint.save(); err != nil {
+		return err
+	}
+
+	if counter == 0 {
+		fmt.Fprintln(os.Stderr, "No entries found.")
 	}
 
 	return nil
 }
 
-// GetEntries fetches and list log entries according to a filter
-func GetEntries(out io.Writer, projectID string, filter string, limit int) error {
-	ctx := context.Background()
-	adminClient, err := logadmin.NewClient(ctx, projectID)
+// fetchEntries prints the historical entries matching a filter and returns the number of entries fetched
+func fetchEntries(ctx context.Context, printer Printer, scopes []Scope, filter string, limit int, order string) (int, error) {
+	iter, err := newMergedIterator(ctx, scopes, filter, limit > 0 || order == OrderDesc)
 	if err != nil {
-		return fmt.Errorf("failed to create logadmin client: \n%w", err)
+		return 0, err
 	}
-	defer adminClient.Close()
+	defer iter.Close()
 
-	options := []logadmin.EntriesOption{logadmin.Filter(filter)}
-	if limit > 0 {
-		options = append(options, logadmin.NewestFirst())
-	}
-
-	iter := adminClient.Entries(ctx, options...)
+	// The newest entries are buffered to be printed oldest first once the limit is reached
+	var buffered []*Entry
+	bufferEntries := limit > 0 && order != OrderDesc
 
 	counter := 0
 	for {
@@ -96,32 +118,56 @@ func GetEntries(out io.Writer, projectID string, filter string, limit int) error
 		if err != nil {
 			// No more log entries
 			if errors.Is(err, iterator.Done) {
-
-				if counter == 0 {
-					fmt.Fprintln(os.Stderr, "No entries found.")
-				}
-
 				break
 			}
 
 			// Unexpected error
-			return err
+			return counter, err
+		}
+
+		counter++
+
+		if bufferEntries {
+			buffered = append(buffered, entry)
+			continue
 		}
 
 		// Print log entries
-		err = printLogEntry(out, entry)
+		err = printer.Print(entry)
 		if err != nil {
-			return err
+			return counter, err
 		}
+	}
 
-		counter++
+	for i := len(buffered) - 1; i >= 0; i-- {
+		if err := printer.Print(buffered[i]); err != nil {
+			return counter, err
+		}
 	}
 
-	return nil
+	return counter, nil
+}
+
+// TailOptions configures how live log entries are tailed
+type TailOptions struct {
+	// Limit stops the stream after this number of live entries, -1 streams until interrupted
+	Limit int
+	// History is fetched once the stream is open and printed before live entries, nil to only print live entries
+	History *History
+	// ReorderWindow holds live entries for this duration to print them in timestamp order, 0 prints them as received
+	ReorderWindow time.Duration
+	// Checkpoint records the entries printed and skips the ones it already holds, nil to not persist the position
+	Checkpoint *Checkpoint
+	// MaxReconnects is the maximum number of consecutive reconnections, -1 for unlimited and 0 to never reconnect
+	MaxReconnects int
 }
 
-// TailLogs fetches and tail live log entries according to a filter
-func TailLogs(out io.Writer, projectID string, filter string, limit int) error {
+// TailLogs fetches and tail live log entries according to a filter.
+// When a history is set, the stream is opened before the historical entries are fetched so that entries written
+// in between are not lost, and entries returned by both the history and the stream are only printed once.
+// Interrupted streams are reopened with a jittered exponential backoff, resuming from the last entry printed.
+// All scopes are tailed by a single stream.
+func TailLogs(printer Printer, scopes []Scope, filter string, options TailOptions) error {
 	// Create a cancellable context
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -133,7 +179,7 @@ func TailLogs(out io.Writer, projectID string, filter string, limit int) error {
 
 	go func() {
 		<-signalChan
-		fmt.Println("\nReceived an interrupt signal, stopping stream...")
+		fmt.Fprintln(os.Stderr, "\nReceived an interrupt signal, stopping stream...")
 		cancel() // stop receiving logs
 	}()
 
@@ -143,58 +189,342 @@ func TailLogs(out io.Writer, projectID string, filter string, limit int) error {
 	}
 	defer client.Close()
 
-	stream, err := client.TailLogEntries(ctx)
+	t := &tailer{
+		client:      client,
+		printer:     printer,
+		scopes:      scopes,
+		filter:      filter,
+		limit:       options.Limit,
+		seen:        newEntrySet(),
+		suppressed:  make(map[string]int),
+		checkpoint:  options.Checkpoint,
+		historyDone: options.History == nil,
+	}
+	defer func() {
+		if err := t.checkpoint.save(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	}()
+
+	if options.ReorderWindow > 0 {
+		t.reorder = newReorderBuffer(options.ReorderWindow)
+	}
+	defer t.printSummary()
+
+	history := options.History
+	attempts := 0
+	for {
+		openedAt := time.Now()
+		received, err := t.session(ctx, history)
+
+		// Respect context cancellation
+		if ctx.Err() != nil || status.Code(err) == codes.Canceled {
+			fmt.Fprintln(os.Stderr, "Streaming stopped successfully")
+			return nil
+		}
+
+		// The limit was reached
+		if err == nil {
+			return nil
+		}
+
+		// Without reconnection, a session ended by the server is a clean stop
+		if options.MaxReconnects == 0 && errors.Is(err, errSessionEnded) {
+			return nil
+		}
+
+		if !isRetryable(err) || options.MaxReconnects == 0 {
+			return err
+		}
+
+		// A session that received entries was healthy, start the backoff over
+		if received > 0 {
+			attempts = 0
+		}
+
+		if options.MaxReconnects > 0 && attempts >= options.MaxReconnects {
+			return fmt.Errorf("giving up after %d reconnection attempts: \n%w", attempts, err)
+		}
+
+		delay := backoff(attempts)
+		attempts++
+		fmt.Fprintf(os.Stderr, "[cloudtail] stream interrupted (%s), reconnecting in %s (attempt %d)\n", describeStreamError(err), delay.Round(100*time.Millisecond), attempts)
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			fmt.Fprintln(os.Stderr, "Streaming stopped successfully")
+			return nil
+		}
+
+		// The requested history is fetched again until it completes once, skipping the entries already printed
+		if !t.historyDone {
+			history = options.History
+			continue
+		}
+
+		// Resume from the last entry printed, or from the start of the interrupted session
+		resumeFrom := t.lastSeen
+		if resumeFrom.IsZero() {
+			resumeFrom = openedAt
+		}
+		history = &History{Filter: resumeFilter(filter, resumeFrom), Limit: -1, Order: OrderAsc, resume: true}
+	}
+}
+
+// tailer streams live entries across sessions, skipping the entries already printed
+type tailer struct {
+	client  *loggingv2.Client
+	printer Printer
+	scopes  []Scope
+	filter  string
+	limit   int
+
+	seen     *entrySet
+	lastSeen time.Time
+	counter  int
+	// suppressed counts the entries dropped by the stream, by reason
+	suppressed map[string]int
+	// reorder holds live entries to print them in timestamp order, nil when disabled
+	reorder *reorderBuffer
+	// checkpoint persists the position of the session, nil when disabled
+	checkpoint *Checkpoint
+	// historyDone is set once the requested history was fetched completely, reconnections then resume after lastSeen
+	historyDone bool
+}
+
+// session opens a stream, prints the history once the stream is open and then the live entries.
+// It returns the number of stream responses received, and a nil error when the limit is reached.
+func (t *tailer) session(ctx context.Context, history *History) (int, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stream, err := t.client.TailLogEntries(ctx)
 	if err != nil {
-		return fmt.Errorf("TailLogEntries error: \n%w", err)
+		return 0, fmt.Errorf("TailLogEntries error: \n%w", err)
 	}
 	defer stream.CloseSend()
 
 	req := &loggingpb.TailLogEntriesRequest{
-		ResourceNames: []string{"projects/" + projectID},
-		Filter:        filter,
+		ResourceNames: resourceNames(t.scopes),
+		Filter:        t.filter,
 	}
 
 	if err := stream.Send(req); err != nil {
-		return fmt.Errorf("stream.Send error: \n%w", err)
+		return 0, fmt.Errorf("stream.Send error: \n%w", err)
 	}
 
-	counter := 0
-	for {
-		resp, err := stream.Recv()
+	// Receive in the background so that live entries are buffered while the history is fetched
+	received := make(chan *loggingpb.TailLogEntriesResponse, tailBufferSize)
+	recvErr := make(chan error, 1)
+	go func() {
+		defer close(received)
+		for {
+			resp, err := stream.Recv()
+			if err != nil {
+				recvErr <- err
+				return
+			}
+
+			select {
+			case received <- resp:
+			case <-ctx.Done():
+				recvErr <- ctx.Err()
+				return
+			}
+		}
+	}()
+
+	responses := 0
+	if history != nil {
+		counter, err := fetchEntries(ctx, printerFunc(t.print), t.scopes, history.Filter, history.Limit, history.Order)
 		if err != nil {
-			// Respect context cancellation
-			if status.Code(err) == codes.Canceled {
-				fmt.Fprintln(out, "Streaming stopped successfully")
-				break
+			if errors.Is(err, errLimitReached) {
+				return responses, nil
 			}
+			return responses, fmt.Errorf("error fetching logs: \n%w", err)
+		}
 
-			// Stream is closed normally
-			if errors.Is(err, io.EOF) {
-				break
+		t.historyDone = true
+
+		if counter == 0 && !history.resume {
+			fmt.Fprintln(os.Stderr, "No entries found.")
+		}
+
+		// Entries received while the history was fetched are printed in timestamp order after it
+		var pending []*Entry
+		for drained := false; !drained; {
+			select {
+			case resp, ok := <-received:
+				if !ok {
+					drained = true
+					break
+				}
+				responses++
+				if err := t.reportSuppressions(resp); err != nil {
+					return responses, err
+				}
+				for _, entry := range resp.GetEntries() {
+					pending = append(pending, entryFromProto(entry))
+				}
+			default:
+				drained = true
 			}
+		}
 
-			// Unexpected error
-			return fmt.Errorf("stream.Recv error: \n%w", err)
+		sortEntries(pending)
+		if err := t.printAll(pending); err != nil {
+			if errors.Is(err, errLimitReached) {
+				return responses, nil
+			}
+			return responses, err
 		}
+	}
 
-		entries := resp.GetEntries()
-		if len(entries) == 0 {
-			continue
+	// Pending entries are released at every tick of the reorder buffer
+	var tick <-chan time.Time
+	if t.reorder != nil {
+		ticker := time.NewTicker(t.reorder.tick())
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+receive:
+	for {
+		select {
+		case resp, ok := <-received:
+			if !ok {
+				break receive
+			}
+
+			responses++
+			if err := t.reportSuppressions(resp); err != nil {
+				return responses, err
+			}
+			for _, entry := range resp.GetEntries() {
+				if err := t.emit(entryFromProto(entry)); err != nil {
+					if errors.Is(err, errLimitReached) {
+						return responses, nil
+					}
+					return responses, err
+				}
+			}
+		case now := <-tick:
+			if err := t.printAll(t.reorder.release(now)); err != nil {
+				if errors.Is(err, errLimitReached) {
+					return responses, nil
+				}
+				return responses, err
+			}
 		}
+	}
 
-		for _, entry := range entries {
-			err = printTailLogEntry(out, entry)
-			if err != nil {
-				return err
+	// Entries still held are printed before the stream is reopened or the tail stops
+	if t.reorder != nil {
+		if err := t.printAll(t.reorder.flush()); err != nil {
+			if errors.Is(err, errLimitReached) {
+				return responses, nil
 			}
+			return responses, err
 		}
+	}
 
-		counter += len(resp.GetEntries())
-		if limit > 0 && counter >= limit {
-			break
+	err = <-recvErr
+
+	// Stream is closed normally, sessions end periodically and are reopened like interrupted ones
+	if errors.Is(err, io.EOF) {
+		return responses, errSessionEnded
+	}
+
+	return responses, fmt.Errorf("stream.Recv error: \n%w", err)
+}
+
+// emit prints a live entry, through the reorder buffer when a reorder window is set
+func (t *tailer) emit(entry *Entry) error {
+	if t.reorder == nil {
+		return t.print(entry)
+	}
+
+	t.reorder.add(entry, time.Now())
+	return nil
+}
+
+// printAll prints entries in order, stopping at the first error
+func (t *tailer) printAll(entries []*Entry) error {
+	for _, entry := range entries {
+		if err := t.print(entry); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// print prints an entry unless it was already printed, and reports errLimitReached once the limit is reached
+func (t *tailer) print(entry *Entry) error {
+	if t.seen.contains(entry) || t.checkpoint.contains(entry) {
+		return nil
+	}
+
+	t.seen.add(entry)
+	if entry.Timestamp.After(t.lastSeen) {
+		t.lastSeen = entry.Timestamp
+
+		// The entries of an incomplete history are all remembered, they are skipped when it is fetched again
+		if t.historyDone {
+			t.seen.prune(t.lastSeen.Add(-handoffWindow))
+		}
+	}
+
+	if err := t.printer.Print(entry); err != nil {
+		return err
+	}
+
+	if err := t.checkpoint.record(entry); err != nil {
+		return err
+	}
+
+	t.counter++
+	if t.limit > 0 && t.counter >= t.limit {
+		return errLimitReached
+	}
+
+	return nil
+}
+
+// reportSuppressions reports the entries the stream dropped because of rate limits or a slow reader.
+// Printers rendering JSON print them as events among entries, other formats report them on stderr.
+func (t *tailer) reportSuppressions(resp *loggingpb.TailLogEntriesResponse) error {
+	for _, info := range resp.GetSuppressionInfo() {
+		suppression := Suppression{Reason: info.GetReason().String(), Count: int(info.GetSuppressedCount())}
+		if suppression.Count == 0 {
+			continue
 		}
 
+		t.suppressed[suppression.Reason] += suppression.Count
+
+		if err := printSuppression(t.printer, suppression); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
+
+// printSummary reports on stderr the total number of entries suppressed during the session
+func (t *tailer) printSummary() {
+	if len(t.suppressed) == 0 {
+		return
+	}
+
+	reasons := slices.Sorted(maps.Keys(t.suppressed))
+
+	total := 0
+	details := make([]string, 0, len(reasons))
+	for _, reason := range reasons {
+		total += t.suppressed[reason]
+		details = append(details, fmt.Sprintf("%s: %d", reason, t.suppressed[reason]))
+	}
+
+	fmt.Fprintf(os.Stderr, "[cloudtail] %d entries suppressed during the session (%s)\n", total, strings.Join(details, ", "))
+}
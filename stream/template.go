@@ -0,0 +1,144 @@
+package stream
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+	"time"
+	"unicode/utf8"
+)
+
+// templateFuncs are the helpers available to user-defined templates, in addition to the text/template builtins
+var templateFuncs = template.FuncMap{
+	// colorSeverity colorizes a severity when writing to a terminal, e.g. {{colorSeverity .Severity}}
+	"colorSeverity": formatSeverity,
+	// ago renders a timestamp relative to now, e.g. {{ago .Timestamp}} gives "5m ago"
+	"ago": func(t time.Time) string {
+		return formatRelativeTime(time.Since(t))
+	},
+	// date formats a timestamp with a Go layout, e.g. {{date "15:04:05" .Timestamp}}
+	"date": func(layout string, t time.Time) string {
+		return t.Format(layout)
+	},
+	// trunc shortens a string to at most n characters, e.g. {{trunc 80 .Message}}
+	"trunc": truncate,
+	// pad right-pads a string with spaces to n characters, e.g. {{pad 9 .Severity}}
+	"pad": func(n int, s string) string {
+		return s + strings.Repeat(" ", max(n-utf8.RuneCountInString(s), 0))
+	},
+	// padLeft left-pads a string with spaces to n characters, e.g. {{padLeft 3 (field "httpRequest.status" .)}}
+	"padLeft": func(n int, value any) string {
+		s := fmt.Sprint(value)
+		return strings.Repeat(" ", max(n-utf8.RuneCountInString(s), 0)) + s
+	},
+	// field looks up a value of the JSON representation by its dotted path, e.g. {{field "jsonPayload.user.id" .}}
+	"field": lookupEntryField,
+	// json encodes a value as compact JSON, e.g. {{json .Labels}}
+	"json": func(value any) (string, error) {
+		encoded, err := json.Marshal(value)
+		return string(encoded), err
+	},
+}
+
+// templatePrinter renders each entry with a user-defined Go text/template
+type templatePrinter struct {
+	out  io.Writer
+	tmpl *template.Template
+	buf  bytes.Buffer
+}
+
+func newTemplatePrinter(out io.Writer, text string) (*templatePrinter, error) {
+	tmpl, err := parseTemplate(text)
+	if err != nil {
+		return nil, err
+	}
+
+	return &templatePrinter{out: out, tmpl: tmpl}, nil
+}
+
+// ValidateTemplate reports whether a template can be parsed, before any output is written
+func ValidateTemplate(text string) error {
+	_, err := parseTemplate(text)
+	return err
+}
+
+func parseTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("entry").Funcs(templateFuncs).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template: \n%w", err)
+	}
+
+	return tmpl, nil
+}
+
+func (p *templatePrinter) Print(entry *Entry) error {
+	p.buf.Reset()
+
+	if err := p.tmpl.Execute(&p.buf, entry); err != nil {
+		return fmt.Errorf("failed to execute template: \n%w", err)
+	}
+
+	// Every entry is written on its own line, unless the template already ends with a newline
+	if !bytes.HasSuffix(p.buf.Bytes(), []byte("\n")) {
+		p.buf.WriteByte('\n')
+	}
+
+	if _, err := p.out.Write(p.buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to write to output: \n%w", err)
+	}
+
+	return nil
+}
+
+func (p *templatePrinter) Close() error {
+	return nil
+}
+
+// lookupEntryField returns the value found at a dotted path (e.g. "resource.labels.pod_name")
+// in the JSON representation of an entry, or an empty string when the path does not exist
+func lookupEntryField(path string, entry *Entry) (any, error) {
+	fields, err := recordFields(newRecord(entry))
+	if err != nil {
+		return nil, err
+	}
+
+	value, ok := lookupField(fields, strings.Split(path, ".")...)
+	if !ok {
+		return "", nil
+	}
+
+	return value, nil
+}
+
+// truncate shortens s to at most n characters, marking the cut with an ellipsis
+func truncate(n int, s string) string {
+	if n <= 0 || utf8.RuneCountInString(s) <= n {
+		return s
+	}
+
+	runes := []rune(s)
+	if n == 1 {
+		return string(runes[:1])
+	}
+
+	return string(runes[:n-1]) + "…"
+}
+
+// formatRelativeTime renders an elapsed duration in its largest unit, e.g. "42s ago" or "3d ago"
+func formatRelativeTime(elapsed time.Duration) string {
+	switch {
+	case elapsed < time.Second:
+		return "just now"
+	case elapsed < time.Minute:
+		return fmt.Sprintf("%ds ago", int(elapsed.Seconds()))
+	case elapsed < time.Hour:
+		return fmt.Sprintf("%dm ago", int(elapsed.Minutes()))
+	case elapsed < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(elapsed.Hours()))
+	default:
+		return fmt.Sprintf("%dd ago", int(elapsed.Hours()/24))
+	}
+}
@@ -0,0 +1,170 @@
+// Package timeexpr parses the human-friendly durations and points in time accepted by the cloudtail flags.
+//
+// Durations extend time.ParseDuration with day (d) and week (w) units, e.g. "2d", "1w" or "1d12h".
+//
+// Points in time can be written as:
+//   - RFC3339 timestamps, e.g. "2026-10-15T09:30:00Z"
+//   - local date and time without an offset, e.g. "2026-10-15 09:30", "2026-10-15T09:30:00" or "2026-10-15"
+//   - day keywords with an optional clock time, e.g. "now", "today", "yesterday 14:00" or "14:00"
+//   - relative durations, e.g. "-90m", "90m ago" or "2d ago"
+//   - Unix epoch seconds or milliseconds, e.g. "1760520600" or "1760520600000"
+package timeexpr
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	durationPattern     = regexp.MustCompile(`^(?:\d+(?:\.\d+)?(?:w|d|h|ms|us|µs|ns|m|s))+$`)
+	durationPartPattern = regexp.MustCompile(`(\d+(?:\.\d+)?)(w|d|h|ms|us|µs|ns|m|s)`)
+	epochPattern        = regexp.MustCompile(`^\d{9,13}$`)
+)
+
+// localLayouts are the layouts of timestamps without an offset, interpreted in the requested location
+var localLayouts = []string{
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// clockLayouts are the layouts of the clock time following a day keyword
+var clockLayouts = []string{"15:04:05", "15:04"}
+
+// ParseDuration parses a duration such as "0", "90m", "2d" or "1w2d". A leading "-" is accepted and ignored,
+// so "-90m" and "90m" both mean 90 minutes ago when used as a lookback.
+func ParseDuration(s string) (time.Duration, error) {
+	value := strings.TrimPrefix(strings.TrimSpace(s), "-")
+
+	// A bare zero needs no unit, as with time.ParseDuration
+	if value == "0" {
+		return 0, nil
+	}
+
+	if !durationPattern.MatchString(value) {
+		return 0, fmt.Errorf("invalid duration %q (e.g. 20s, 30m, 1h15m, 2d, 1w)", s)
+	}
+
+	var total time.Duration
+	for _, part := range durationPartPattern.FindAllStringSubmatch(value, -1) {
+		number, unit := part[1], part[2]
+
+		switch unit {
+		case "w", "d":
+			days, err := strconv.ParseFloat(number, 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid duration %q: %w", s, err)
+			}
+			if unit == "w" {
+				days *= 7
+			}
+			total += time.Duration(days * float64(24*time.Hour))
+		default:
+			d, err := time.ParseDuration(number + unit)
+			if err != nil {
+				return 0, fmt.Errorf("invalid duration %q: %w", s, err)
+			}
+			total += d
+		}
+	}
+
+	return total, nil
+}
+
+// Parse parses a point in time relative to now. Timestamps and clock times without an offset are interpreted in loc.
+func Parse(s string, now time.Time, loc *time.Location) (time.Time, error) {
+	value := strings.TrimSpace(s)
+	lower := strings.ToLower(value)
+
+	if loc == nil {
+		loc = time.Local
+	}
+
+	if value == "" {
+		return time.Time{}, fmt.Errorf("empty time expression")
+	}
+
+	if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return t, nil
+	}
+
+	for _, layout := range localLayouts {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, nil
+		}
+	}
+
+	if epochPattern.MatchString(value) {
+		epoch, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid epoch %q: %w", s, err)
+		}
+
+		// Thirteen digits are milliseconds, fewer are seconds
+		if len(value) == 13 {
+			return time.UnixMilli(epoch), nil
+		}
+		return time.Unix(epoch, 0), nil
+	}
+
+	if relative, found := strings.CutSuffix(lower, " ago"); found {
+		d, err := ParseDuration(relative)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return now.Add(-d), nil
+	}
+
+	if strings.HasPrefix(lower, "-") {
+		d, err := ParseDuration(lower)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return now.Add(-d), nil
+	}
+
+	return parseDayExpression(s, lower, now.In(loc))
+}
+
+// parseDayExpression parses "now", "today", "yesterday" and "tomorrow", optionally followed by a clock time,
+// as well as a clock time alone meaning today
+func parseDayExpression(s string, lower string, now time.Time) (time.Time, error) {
+	day, clock, _ := strings.Cut(lower, " ")
+
+	if day == "now" && clock == "" {
+		return now, nil
+	}
+
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	switch day {
+	case "today":
+	case "yesterday":
+		midnight = midnight.AddDate(0, 0, -1)
+	case "tomorrow":
+		midnight = midnight.AddDate(0, 0, 1)
+	default:
+		// A clock time alone, e.g. "14:00", means today
+		clock = lower
+	}
+
+	clock = strings.TrimSpace(clock)
+	if clock == "" {
+		return midnight, nil
+	}
+
+	for _, layout := range clockLayouts {
+		if t, err := time.Parse(layout, clock); err == nil {
+			return time.Date(midnight.Year(), midnight.Month(), midnight.Day(), t.Hour(), t.Minute(), t.Second(), 0, now.Location()), nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("invalid time expression %q (e.g. 2026-10-15T09:30:00Z, \"2026-10-15 09:30\", \"yesterday 14:00\", today, -90m, 1760520600)", s)
+}
@@ -0,0 +1,137 @@
+package timeexpr
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{input: "0", want: 0},
+		{input: "-0", want: 0},
+		{input: "20s", want: 20 * time.Second},
+		{input: "90m", want: 90 * time.Minute},
+		{input: "-90m", want: 90 * time.Minute},
+		{input: "1h15m", want: time.Hour + 15*time.Minute},
+		{input: "2d", want: 48 * time.Hour},
+		{input: "1.5d", want: 36 * time.Hour},
+		{input: "1w", want: 7 * 24 * time.Hour},
+		{input: "1w2d", want: 9 * 24 * time.Hour},
+		{input: "1d12h", want: 36 * time.Hour},
+		{input: " 30m ", want: 30 * time.Minute},
+		{input: "", wantErr: true},
+		{input: "10", wantErr: true},
+		{input: "2y", wantErr: true},
+		{input: "d", wantErr: true},
+		{input: "1h 30m", wantErr: true},
+		{input: "abc", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseDuration(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseDuration(%q) = %v, want an error", tt.input, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseDuration(%q) returned an error: %v", tt.input, err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseDuration(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	// 2026-10-15 09:30 UTC is 11:30 in the pinned location (UTC+2)
+	now := time.Date(2026, 10, 15, 9, 30, 0, 0, time.UTC)
+	loc := time.FixedZone("UTC+2", 2*60*60)
+
+	tests := []struct {
+		input   string
+		want    time.Time
+		wantErr bool
+	}{
+		// RFC3339 timestamps keep their offset
+		{input: "2026-10-15T09:30:00Z", want: time.Date(2026, 10, 15, 9, 30, 0, 0, time.UTC)},
+		{input: "2026-10-15T09:30:00.123+05:00", want: time.Date(2026, 10, 15, 4, 30, 0, 123_000_000, time.UTC)},
+
+		// Layouts without an offset are interpreted in loc
+		{input: "2026-10-15T09:30:00", want: time.Date(2026, 10, 15, 9, 30, 0, 0, loc)},
+		{input: "2026-10-15T09:30", want: time.Date(2026, 10, 15, 9, 30, 0, 0, loc)},
+		{input: "2026-10-15 09:30", want: time.Date(2026, 10, 15, 9, 30, 0, 0, loc)},
+		{input: "2026-10-15 09:30:05.5", want: time.Date(2026, 10, 15, 9, 30, 5, 500_000_000, loc)},
+		{input: "2026-10-15", want: time.Date(2026, 10, 15, 0, 0, 0, 0, loc)},
+
+		// Day keywords are relative to now in loc
+		{input: "now", want: now},
+		{input: "today", want: time.Date(2026, 10, 15, 0, 0, 0, 0, loc)},
+		{input: "yesterday", want: time.Date(2026, 10, 14, 0, 0, 0, 0, loc)},
+		{input: "yesterday 14:00", want: time.Date(2026, 10, 14, 14, 0, 0, 0, loc)},
+		{input: "Yesterday 14:00:30", want: time.Date(2026, 10, 14, 14, 0, 30, 0, loc)},
+		{input: "tomorrow 08:15", want: time.Date(2026, 10, 16, 8, 15, 0, 0, loc)},
+		{input: "14:00", want: time.Date(2026, 10, 15, 14, 0, 0, 0, loc)},
+
+		// Relative durations
+		{input: "-90m", want: now.Add(-90 * time.Minute)},
+		{input: "2h ago", want: now.Add(-2 * time.Hour)},
+		{input: "2d ago", want: now.Add(-48 * time.Hour)},
+		{input: "1w ago", want: now.Add(-7 * 24 * time.Hour)},
+
+		// Unix epochs, ten digits are seconds and thirteen are milliseconds
+		{input: "1760520600", want: time.Unix(1760520600, 0)},
+		{input: "1760520600123", want: time.UnixMilli(1760520600123)},
+
+		// Errors
+		{input: "", wantErr: true},
+		{input: "   ", wantErr: true},
+		{input: "yesterday noon", wantErr: true},
+		{input: "25:00", wantErr: true},
+		{input: "2026-13-01", wantErr: true},
+		{input: "2h from now", wantErr: true},
+		{input: "-2y", wantErr: true},
+		{input: "x ago", wantErr: true},
+		{input: "12345", wantErr: true},
+		{input: "last week", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := Parse(tt.input, now, loc)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Parse(%q) = %v, want an error", tt.input, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse(%q) returned an error: %v", tt.input, err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("Parse(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDefaultsToLocal(t *testing.T) {
+	now := time.Date(2026, 10, 15, 9, 30, 0, 0, time.UTC)
+
+	got, err := Parse("2026-10-15 09:30", now, nil)
+	if err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	want := time.Date(2026, 10, 15, 9, 30, 0, 0, time.Local)
+	if !got.Equal(want) {
+		t.Errorf("Parse with a nil location = %v, want %v", got, want)
+	}
+}